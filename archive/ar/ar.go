@@ -19,6 +19,7 @@ package ar
 import (
 	"bufio"
 	"fmt"
+	"github.com/openxo/goxc/core"
 	"io"
 	"log"
 	"os"
@@ -62,6 +63,10 @@ func ArForDeb(archiveFilename string, items [][]string) error {
 					return err
 				} else {
 					fmodTimestamp := fmt.Sprintf("%d", finf.ModTime().Unix())
+					if t, isReproducible := core.GetReproducibleTime(); isReproducible {
+						//0.11.x
+						fmodTimestamp = fmt.Sprintf("%d", t.Unix())
+					}
 					//use root (for deb). These files are only for dpkg to extract as root anyway
 					//this behaviour could be made configurable if 'Ar' gets used for anything else beyond .deb creation
 					uid := "0"
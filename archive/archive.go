@@ -18,11 +18,13 @@ package archive
 */
 
 import (
+	"fmt"
 	"path/filepath"
 	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
 	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
 	"github.com/openxo/goxc/config"
 	"github.com/openxo/goxc/core"
+	"sort"
 )
 
 // type definition representing a file to be archived. Details location on filesystem and destination filename inside archive.
@@ -75,6 +77,40 @@ func ArchiveBinariesAndResources(outDir, platName string, binPaths []string, app
 		}
 		toArchive = append(toArchive, ArchiveItemFromFileSystem(resource, destFile))
 	}
-	err = archiver(filepath.Join(outDir, zipFilename), toArchive)
+	err = RunArchiver(archiver, filepath.Join(outDir, zipFilename), toArchive)
 	return
 }
+
+// Runs the archiver via core.RunOp, so that a dry run records the archive & its entries instead.
+// 0.11.x
+func RunArchiver(archiver Archiver, archiveFilename string, itemsToArchive []ArchiveItem) error {
+	entries := []string{}
+	for _, item := range itemsToArchive {
+		if item.FileSystemPath != "" {
+			entries = append(entries, item.ArchivePath+" <- "+item.FileSystemPath)
+		} else {
+			entries = append(entries, fmt.Sprintf("%s (%d bytes)", item.ArchivePath, len(item.Data)))
+		}
+	}
+	return core.RunOp(core.Op{Kind: core.OP_ARCHIVE, Description: archiveFilename, Details: entries}, func() error {
+		return archiver(archiveFilename, itemsToArchive)
+	})
+}
+
+// 0.11.x for reproducible builds, items are archived in order of their archive paths. Otherwise, as given
+func reproducibleOrder(items []ArchiveItem) []ArchiveItem {
+	if _, isReproducible := core.GetReproducibleTime(); !isReproducible {
+		return items
+	}
+	sorted := append([]ArchiveItem{}, items...)
+	sort.Stable(archiveItemsByPath(sorted))
+	return sorted
+}
+
+type archiveItemsByPath []ArchiveItem
+
+func (a archiveItemsByPath) Len() int      { return len(a) }
+func (a archiveItemsByPath) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a archiveItemsByPath) Less(i, j int) bool {
+	return filepath.ToSlash(a[i].ArchivePath) < filepath.ToSlash(a[j].ArchivePath)
+}
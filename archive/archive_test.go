@@ -0,0 +1,58 @@
+package archive
+
+import (
+	"bytes"
+	"github.com/openxo/goxc/core"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReproducibleArchives(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "goxc-archive")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fixed := time.Unix(1700000000, 0).UTC()
+	core.SetReproducibleTime(&fixed)
+	defer core.SetReproducibleTime(nil)
+	for _, archiver := range []Archiver{TarGz, Zip} {
+		archives := [][]byte{}
+		for i, modTime := range []time.Time{time.Unix(1500000000, 0), time.Unix(1600000000, 0)} {
+			items := []ArchiveItem{}
+			//different modification times & order each time
+			for _, name := range []string{"b.txt", "a.txt"} {
+				path := filepath.Join(tmpDir, name)
+				err = ioutil.WriteFile(path, []byte(name), 0600)
+				if err == nil {
+					err = os.Chtimes(path, modTime, modTime)
+				}
+				if err != nil {
+					t.Fatalf("%v", err)
+				}
+				item := ArchiveItemFromFileSystem(path, name)
+				if i == 0 {
+					items = append(items, item)
+				} else {
+					items = append([]ArchiveItem{item}, items...)
+				}
+			}
+			archivePath := filepath.Join(tmpDir, "archive")
+			err = archiver(archivePath, items)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			content, err := ioutil.ReadFile(archivePath)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			archives = append(archives, content)
+		}
+		if !bytes.Equal(archives[0], archives[1]) {
+			t.Errorf("Archives differ")
+		}
+	}
+}
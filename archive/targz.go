@@ -19,9 +19,11 @@ package archive
 import (
 	"archive/tar"
 	"compress/gzip"
+	"github.com/openxo/goxc/core"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -42,7 +44,7 @@ func TarGz(archiveFilename string, itemsToArchive []ArchiveItem) error {
 	tw := tar.NewWriter(gw)
 	defer tw.Close()
 
-	for _, item := range itemsToArchive {
+	for _, item := range reproducibleOrder(itemsToArchive) {
 		err = addItemToTarGz(item, tw)
 		if err != nil {
 			return err
@@ -64,6 +66,7 @@ func TarGzWrite(item ArchiveItem, tw *tar.Writer, fi os.FileInfo) (err error) {
 			h.Size = fi.Size()
 			h.Mode = int64(fi.Mode())
 			h.ModTime = fi.ModTime()
+			reproducibleTarHeader(h)
 
 			err = tw.WriteHeader(h)
 
@@ -76,6 +79,7 @@ func TarGzWrite(item ArchiveItem, tw *tar.Writer, fi os.FileInfo) (err error) {
 		//backslash-only paths
 		h.Name = strings.Replace(item.ArchivePath, "\\", "/", -1)
 		h.Size = int64(len(item.Data))
+		reproducibleTarHeader(h)
 		err = tw.WriteHeader(h)
 		if err == nil {
 			_, err = tw.Write(item.Data)
@@ -107,6 +111,9 @@ func addDirectoryToTarGz(dirPath ArchiveItem, tw *tar.Writer) error {
 		defer dir.Close()
 		fis, err := dir.Readdir(0)
 		if err == nil {
+			if _, isReproducible := core.GetReproducibleTime(); isReproducible {
+				sort.Sort(fileInfosByName(fis))
+			}
 			for _, fi := range fis {
 				curPath := ArchiveItemFromFileSystem(filepath.Join(dirPath.FileSystemPath, fi.Name()), filepath.Join(dirPath.ArchivePath, fi.Name()))
 				addItemToTarGz(curPath, tw)
@@ -115,3 +122,19 @@ func addDirectoryToTarGz(dirPath ArchiveItem, tw *tar.Writer) error {
 	}
 	return err
 }
+
+// 0.11.x for reproducible builds: a fixed time, root ownership & normalised modes
+func reproducibleTarHeader(h *tar.Header) {
+	if t, isReproducible := core.GetReproducibleTime(); isReproducible {
+		h.ModTime = t
+		h.Uid, h.Gid = 0, 0
+		h.Uname, h.Gname = "", ""
+		h.Mode = int64(core.ReproducibleMode(os.FileMode(h.Mode)))
+	}
+}
+
+type fileInfosByName []os.FileInfo
+
+func (a fileInfosByName) Len() int           { return len(a) }
+func (a fileInfosByName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a fileInfosByName) Less(i, j int) bool { return a[i].Name() < a[j].Name() }
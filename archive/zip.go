@@ -18,6 +18,7 @@ package archive
 
 import (
 	"archive/zip"
+	"github.com/openxo/goxc/core"
 	"io"
 	"os"
 	"strings"
@@ -35,7 +36,7 @@ func Zip(zipFilename string, itemsToArchive []ArchiveItem) error {
 	defer zw.Close()
 
 	//resources
-	for _, item := range itemsToArchive {
+	for _, item := range reproducibleOrder(itemsToArchive) {
 		err = addFileToZIP(zw, item)
 		if err != nil {
 			return err
@@ -55,6 +56,11 @@ func addFileToZIP(zw *zip.Writer, item ArchiveItem) (err error) {
 		return
 	}
 	header.Method = zip.Deflate
+	if t, isReproducible := core.GetReproducibleTime(); isReproducible {
+		//0.11.x a fixed time & normalised mode
+		header.Modified = t
+		header.SetMode(core.ReproducibleMode(binfo.Mode()))
+	}
 	//always use forward slashes even on Windows
 	header.Name = strings.Replace(item.ArchivePath, "\\", "/", -1)
 	w, err := zw.CreateHeader(header)
@@ -0,0 +1,108 @@
+package config
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"github.com/openxo/goxc/typeutils"
+	"path"
+	"strings"
+)
+
+// v0.11.x settings for individual binaries, for repositories with several main packages.
+// Binaries are keyed by main dir, relative to the working directory, with forward slashes (e.g. "cmd/server", or "." for the working directory itself).
+type BinarySettings struct {
+	//the executable's name. Defaults to the main dir's name
+	Name string `json:",omitempty"`
+	//overrides BuildSettings (& any PlatformOverrides) for this binary
+	BuildSettings *BuildSettings `json:",omitempty"`
+	//the platforms to build this binary for, as build constraints. Only platforms which are being built anyway are included. Empty means all of them
+	BuildConstraints string `json:",omitempty"`
+	//whether the binary is included in archives & packages. Defaults to true
+	Packaged *bool `json:",omitempty"`
+}
+
+func binariesFromMap(v interface{}, k string) (map[string]BinarySettings, error) {
+	m, err := typeutils.ToMap(v, k)
+	if err != nil {
+		return nil, err
+	}
+	binaries := map[string]BinarySettings{}
+	for mainDir, binaryV := range m {
+		binaryM, err := typeutils.ToMap(binaryV, k+":"+mainDir)
+		if err != nil {
+			return nil, err
+		}
+		binary := BinarySettings{}
+		for k2, v2 := range binaryM {
+			switch k2 {
+			case "Name":
+				binary.Name, err = typeutils.ToString(v2, k+":"+mainDir+":"+k2)
+			case "BuildSettings":
+				var bsM map[string]interface{}
+				bsM, err = typeutils.ToMap(v2, k+":"+mainDir+":"+k2)
+				if err == nil {
+					binary.BuildSettings, err = buildSettingsFromMap(bsM)
+				}
+			case "BuildConstraints":
+				binary.BuildConstraints, err = typeutils.ToString(v2, k+":"+mainDir+":"+k2)
+			case "Packaged":
+				var packaged bool
+				packaged, err = typeutils.ToBool(v2, k+":"+mainDir+":"+k2)
+				if err == nil {
+					binary.Packaged = &packaged
+				}
+			default:
+				//unrecognised settings are reported by schema validation
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		binaries[mainDir] = binary
+	}
+	return binaries, nil
+}
+
+// Normalises a Binaries key (or a relative main dir), e.g. "./cmd/server/" becomes "cmd/server"
+func BinaryKey(relativeMainDir string) string {
+	return path.Clean(strings.Replace(relativeMainDir, "\\", "/", -1))
+}
+
+// The settings for a binary, given its main dir relative to the working directory
+func (s Settings) GetBinarySettings(relativeMainDir string) BinarySettings {
+	key := BinaryKey(relativeMainDir)
+	for k, binary := range s.Binaries {
+		if BinaryKey(k) == key {
+			return binary
+		}
+	}
+	return BinarySettings{}
+}
+
+// The effective settings for a binary: its BuildSettings take priority over BuildSettings (& over any PlatformOverrides already applied).
+func (s Settings) ForBinary(relativeMainDir string) Settings {
+	binary := s.GetBinarySettings(relativeMainDir)
+	if binary.BuildSettings == nil {
+		return s
+	}
+	return Merge(Settings{BuildSettings: binary.BuildSettings}, s)
+}
+
+// Whether the binary is included in archives & packages
+func (b BinarySettings) IsPackaged() bool {
+	return b.Packaged == nil || *b.Packaged
+}
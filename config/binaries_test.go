@@ -0,0 +1,41 @@
+package config
+
+import (
+	"github.com/openxo/goxc/platforms"
+	"testing"
+)
+
+func TestForBinary(t *testing.T) {
+	m := map[string]interface{}{
+		"BuildSettings": map[string]interface{}{"LdFlags": "-s", "Tags": "base"},
+		"PlatformOverrides": map[string]interface{}{
+			"linux": map[string]interface{}{
+				"BuildSettings": map[string]interface{}{"Tags": "linux"}}},
+		"Binaries": map[string]interface{}{
+			"./cmd/server/": map[string]interface{}{
+				"Name":          "app-server",
+				"BuildSettings": map[string]interface{}{"Tags": "server"}},
+			"cmd/debug": map[string]interface{}{
+				"BuildConstraints": "linux",
+				"Packaged":         false}}}
+	settings, err := loadSettingsSection(m)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	server := settings.GetBinarySettings("cmd/server")
+	if server.Name != "app-server" || !server.IsPackaged() {
+		t.Errorf("Unexpected binary settings: %+v", server)
+	}
+	debug := settings.GetBinarySettings("cmd/debug")
+	if debug.BuildConstraints != "linux" || debug.IsPackaged() {
+		t.Errorf("Unexpected binary settings: %+v", debug)
+	}
+	linux := settings.ForPlatform(platforms.Platform{Os: platforms.LINUX, Arch: platforms.AMD64})
+	serverLinux := linux.ForBinary("cmd/server")
+	if *serverLinux.BuildSettings.Tags != "server" || *serverLinux.BuildSettings.LdFlags != "-s" {
+		t.Errorf("Unexpected build settings: %+v", serverLinux.BuildSettings)
+	}
+	if *linux.ForBinary("cmd/debug").BuildSettings.Tags != "linux" {
+		t.Errorf("Binary without BuildSettings should keep the platform's settings")
+	}
+}
@@ -2,7 +2,6 @@ package config
 
 import (
 	"github.com/openxo/goxc/typeutils"
-	"log"
 )
 
 type BuildSettings struct {
@@ -20,6 +19,8 @@ type BuildSettings struct {
 	LdFlags       *string                 `json:",omitempty"`
 	LdFlagsXVars  *map[string]interface{} `json:",omitempty"`
 	Tags          *string                 `json:",omitempty"`
+	Mod           *string                 `json:",omitempty"` //0.11.x 'go build -mod' (readonly, vendor or mod). Only for modules
+	Reproducible  *bool                   `json:",omitempty"` //0.11.x -trimpath, -buildvcs=false & fixed timestamps. See core.GetSourceDateEpoch
 	ExtraArgs     []string                `json:",omitempty"`
 }
 
@@ -103,6 +104,18 @@ func buildSettingsFromMap(m map[string]interface{}) (*BuildSettings, error) {
 			if err == nil {
 				bs.Tags = &s
 			}
+		case "Reproducible":
+			var reproducible bool
+			reproducible, err = typeutils.ToBool(v, k)
+			if err == nil {
+				bs.Reproducible = &reproducible
+			}
+		case "Mod":
+			var s string
+			s, err = typeutils.ToString(v, k)
+			if err == nil {
+				bs.Mod = &s
+			}
 		case "LdFlagsXVars":
 			var xVars map[string]interface{}
 			xVars, err = typeutils.ToMap(v, k)
@@ -112,7 +125,7 @@ func buildSettingsFromMap(m map[string]interface{}) (*BuildSettings, error) {
 		case "ExtraArgs":
 			bs.ExtraArgs, err = typeutils.ToStringSlice(v, k)
 		default:
-			log.Printf("Warning!! Unrecognised Setting '%s' (value %v)", k, v)
+			//0.11.x unrecognised settings are reported by schema validation
 		}
 		if err != nil {
 			return &bs, err
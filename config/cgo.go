@@ -0,0 +1,120 @@
+package config
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"github.com/openxo/goxc/typeutils"
+	"strings"
+)
+
+// the go tool's default CGO_CFLAGS, CGO_CXXFLAGS & CGO_LDFLAGS
+const CGO_DEFAULT_FLAGS = "-g -O2"
+
+// v0.11.x cgo settings: the C toolchain for a target platform.
+// Normally set in PlatformOverrides, e.g. "linux,arm64": {"Cgo": {"CC": "aarch64-linux-gnu-gcc", "Sysroot": "/usr/aarch64-linux-gnu"}}
+type CgoSettings struct {
+	//sets CGO_ENABLED. Defaults to enabled when CC is set, otherwise the go tool decides (disabled when cross-compiling)
+	Enabled *bool `json:",omitempty"`
+	//the C & C++ compilers (CC & CXX). Either a command on the PATH or a path, optionally followed by arguments (e.g. "zig cc -target aarch64-linux-musl")
+	CC  string `json:",omitempty"`
+	CXX string `json:",omitempty"`
+	//passed to the compilers & linker as --sysroot
+	Sysroot string `json:",omitempty"`
+	//CGO_CFLAGS, CGO_CXXFLAGS & CGO_LDFLAGS
+	CFlags   string `json:",omitempty"`
+	CXXFlags string `json:",omitempty"`
+	LdFlags  string `json:",omitempty"`
+}
+
+func cgoSettingsFromMap(v interface{}, k string) (*CgoSettings, error) {
+	m, err := typeutils.ToMap(v, k)
+	if err != nil {
+		return nil, err
+	}
+	cgo := CgoSettings{}
+	for k2, v2 := range m {
+		switch k2 {
+		case "Enabled":
+			var enabled bool
+			enabled, err = typeutils.ToBool(v2, k+":"+k2)
+			if err == nil {
+				cgo.Enabled = &enabled
+			}
+		case "CC":
+			cgo.CC, err = typeutils.ToString(v2, k+":"+k2)
+		case "CXX":
+			cgo.CXX, err = typeutils.ToString(v2, k+":"+k2)
+		case "Sysroot":
+			cgo.Sysroot, err = typeutils.ToString(v2, k+":"+k2)
+		case "CFlags":
+			cgo.CFlags, err = typeutils.ToString(v2, k+":"+k2)
+		case "CXXFlags":
+			cgo.CXXFlags, err = typeutils.ToString(v2, k+":"+k2)
+		case "LdFlags":
+			cgo.LdFlags, err = typeutils.ToString(v2, k+":"+k2)
+		default:
+			//unrecognised settings are reported by schema validation
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &cgo, nil
+}
+
+// Whether cgo is explicitly enabled or disabled. 'isSet' is false when it's up to the go tool
+func (c CgoSettings) IsEnabled() (isEnabled, isSet bool) {
+	if c.Enabled != nil {
+		return *c.Enabled, true
+	}
+	if c.CC != "" {
+		return true, true
+	}
+	return false, false
+}
+
+// The environment for 'go build' (CGO_ENABLED, CC, CXX, CGO_CFLAGS, CGO_CXXFLAGS, CGO_LDFLAGS)
+func (c CgoSettings) Env() []string {
+	env := []string{}
+	isEnabled, isSet := c.IsEnabled()
+	if isSet && !isEnabled {
+		return []string{"CGO_ENABLED=0"}
+	}
+	if isSet {
+		env = append(env, "CGO_ENABLED=1")
+	}
+	if c.CC != "" {
+		env = append(env, "CC="+c.CC)
+	}
+	if c.CXX != "" {
+		env = append(env, "CXX="+c.CXX)
+	}
+	for _, flags := range [][]string{{"CGO_CFLAGS", c.CFlags}, {"CGO_CXXFLAGS", c.CXXFlags}, {"CGO_LDFLAGS", c.LdFlags}} {
+		value := flags[1]
+		if c.Sysroot != "" {
+			if value == "" {
+				//the go tool's default, which setting the variable would otherwise lose
+				value = CGO_DEFAULT_FLAGS
+			}
+			value = strings.TrimSpace(value + " --sysroot=" + c.Sysroot)
+		}
+		if value != "" {
+			env = append(env, flags[0]+"="+value)
+		}
+	}
+	return env
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"github.com/openxo/goxc/platforms"
+	"testing"
+)
+
+func TestCgoEnv(t *testing.T) {
+	m := map[string]interface{}{
+		"Cgo": map[string]interface{}{"Enabled": false},
+		"PlatformOverrides": map[string]interface{}{
+			"linux,arm64": map[string]interface{}{
+				"Cgo": map[string]interface{}{"CC": "aarch64-linux-gnu-gcc", "Sysroot": "/sysroot", "LdFlags": "-static"}}}}
+	settings, err := loadSettingsSection(m)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	arm64 := settings.ForPlatform(platforms.Platform{Os: platforms.LINUX, Arch: "arm64"})
+	expected := []string{"CGO_ENABLED=1", "CC=aarch64-linux-gnu-gcc", "CGO_CFLAGS=-g -O2 --sysroot=/sysroot", "CGO_CXXFLAGS=-g -O2 --sysroot=/sysroot", "CGO_LDFLAGS=-static --sysroot=/sysroot"}
+	//the override's CC doesn't re-enable cgo, as 'Enabled: false' is inherited
+	if env := arm64.Cgo.Env(); len(env) != 1 || env[0] != "CGO_ENABLED=0" {
+		t.Errorf("Unexpected env %v", env)
+	}
+	arm64.Cgo.Enabled = nil
+	env := arm64.Cgo.Env()
+	if len(env) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, env)
+	}
+	for i := range expected {
+		if env[i] != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], env[i])
+		}
+	}
+	if len((CgoSettings{}).Env()) != 0 {
+		t.Errorf("Empty Cgo settings should leave cgo to the go tool")
+	}
+}
@@ -0,0 +1,150 @@
+package config
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"fmt"
+	"github.com/openxo/goxc/core"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config inheritance (0.11.x)
+// A config file can name one or more parent files with "Extends" (a string or a list).
+// Each parent ranks just below the file which extends it, and above any less important files.
+// A parent shared by several files ranks below all of them (i.e. at the lowest rank of any file which extends it).
+// Relative paths are relative to the extending file, or failing that, to the user config dir (e.g. ~/.config/goxc).
+type loadedConfig struct {
+	File        string
+	SettingsMap map[string]interface{}
+	RawJson     []byte
+	//absolute path, for spotting shared parents
+	key string
+}
+
+// Loads a config file, then (optionally) its parents, appending each to 'loaded' (most-important first).
+// 'children' are the files which (indirectly) extend this one, for detecting cycles.
+// A shared parent is appended once per file which extends it (see lowestRanked).
+func loadJsonConfigAndParents(jsonFile string, children []string, isFollowExtends bool, loaded *[]loadedConfig, verbose bool) error {
+	key, err := filepath.Abs(jsonFile)
+	if err != nil {
+		return err
+	}
+	for i, child := range children {
+		if child == key {
+			return fmt.Errorf("config inheritance cycle: %s -> %s", strings.Join(children[i:], " -> "), key)
+		}
+	}
+	settingsMap, rawJson, err := loadJsonFileAsMapAndRaw(jsonFile, verbose)
+	if err != nil {
+		return err
+	}
+	//0.11.x ${ENV_VAR}, ${env:NAME:-default} & ${file:/path/to/secret}
+	settingsMap, err = interpolateSettingsMap(settingsMap, filepath.Dir(jsonFile))
+	if err != nil {
+		log.Printf("ERROR (%s): %v", jsonFile, err)
+		return err
+	}
+	*loaded = append(*loaded, loadedConfig{jsonFile, settingsMap, rawJson, key})
+	if !isFollowExtends {
+		return nil
+	}
+	parents, err := extendsFromValue(settingsMap["Extends"], "Extends")
+	if err != nil {
+		return fmt.Errorf("%s: %v", jsonFile, err)
+	}
+	ancestry := append(append([]string{}, children...), key)
+	for _, parent := range parents {
+		parentFile := resolveParentConfig(parent, filepath.Dir(jsonFile))
+		if verbose {
+			log.Printf("%s extends %s", jsonFile, parentFile)
+		}
+		err = loadJsonConfigAndParents(parentFile, ancestry, isFollowExtends, loaded, verbose)
+		if err != nil {
+			if os.IsNotExist(err) {
+				//a missing parent is an error (unlike the standard config files)
+				return fmt.Errorf("%s: Extends '%s': file not found", jsonFile, parent)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Removes all but the last (least important) occurrence of each file.
+// So a shared parent ranks below every file which extends it, rather than just below the first one to be loaded.
+func lowestRanked(loaded []loadedConfig) []loadedConfig {
+	last := map[string]int{}
+	for i, loadedFile := range loaded {
+		last[loadedFile.key] = i
+	}
+	ret := []loadedConfig{}
+	for i, loadedFile := range loaded {
+		if last[loadedFile.key] == i {
+			ret = append(ret, loadedFile)
+		}
+	}
+	return ret
+}
+
+// 'Extends' can be a string or a list of strings
+func extendsFromValue(v interface{}, k string) ([]string, error) {
+	switch typedV := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{typedV}, nil
+	case []interface{}:
+		ret := []string{}
+		for _, item := range typedV {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s should be a json string or array of strings, not containing a %T", k, item)
+			}
+			ret = append(ret, s)
+		}
+		return ret, nil
+	}
+	return nil, fmt.Errorf("%s should be a json string or array of strings, not a %T", k, v)
+}
+
+// relative to the extending file's dir, or the user config dir
+func resolveParentConfig(parent, dir string) string {
+	if filepath.IsAbs(parent) {
+		return parent
+	}
+	relative := filepath.Join(dir, parent)
+	if _, err := os.Stat(relative); err == nil {
+		return relative
+	}
+	fromUserConfigDir := filepath.Join(UserConfigDir(), parent)
+	if _, err := os.Stat(fromUserConfigDir); err == nil {
+		return fromUserConfigDir
+	}
+	return relative
+}
+
+// e.g. ~/.config/goxc (or %AppData%\goxc on Windows)
+func UserConfigDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = filepath.Join(core.UserHomeDir(), ".config")
+	}
+	return filepath.Join(dir, "goxc")
+}
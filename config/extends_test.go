@@ -0,0 +1,75 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtends(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-extends")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string) string {
+		fileName := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(fileName), 0755)
+		ioutil.WriteFile(fileName, []byte(content), 0644)
+		return fileName
+	}
+	write("org/base.json", `{"ConfigVersion": "0.9", "PackageVersion": "0.0.1", "BuildConstraints": "linux", "TaskSettings": {"bintray": {"subject": "org", "repository": "tools"}}}`)
+	write("org/team.json", `{"ConfigVersion": "0.9", "Extends": "base.json", "BuildConstraints": "linux windows", "TaskSettings": {"bintray": {"repository": "team-tools"}}}`)
+	main := write("app/.goxc.json", `{"ConfigVersion": "0.9", "Extends": ["../org/team.json", "../org/base.json"], "PackageVersion": "1.0.0"}`)
+
+	settings, err := LoadJsonConfigs(dir, []string{main}, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if settings.PackageVersion != "1.0.0" || settings.BuildConstraints != "linux windows" {
+		t.Errorf("Unexpected precedence: %+v", settings)
+	}
+	if settings.GetTaskSettingString("bintray", "subject") != "org" || settings.GetTaskSettingString("bintray", "repository") != "team-tools" {
+		t.Errorf("Unexpected task settings: %+v", settings.TaskSettings)
+	}
+	settings, err = LoadJsonConfigsWithoutExtends(dir, []string{main}, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if settings.BuildConstraints != "" || len(settings.Extends) != 2 {
+		t.Errorf("Parents should not be loaded: %+v", settings)
+	}
+
+	write("org/base.json", `{"ConfigVersion": "0.9", "Extends": "../app/.goxc.json"}`)
+	_, err = LoadJsonConfigs(dir, []string{main}, false)
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Expected a cycle error, got %v", err)
+	}
+	write("org/base.json", `{"ConfigVersion": "0.9", "Extends": "missing.json"}`)
+	_, err = LoadJsonConfigs(dir, []string{main}, false)
+	if err == nil || os.IsNotExist(err) {
+		t.Errorf("Expected an error for a missing parent, got %v", err)
+	}
+}
+
+func TestExtendsSharedParent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-extends")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "base.json"), []byte(`{"ConfigVersion": "0.9", "PackageVersion": "1.0", "BuildConstraints": "linux"}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, ".goxc.local.json"), []byte(`{"ConfigVersion": "0.9", "Extends": "base.json"}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, ".goxc.json"), []byte(`{"ConfigVersion": "0.9", "Extends": "base.json", "PackageVersion": "2.0"}`), 0644)
+
+	settings, err := LoadJsonConfigs(dir, []string{filepath.Join(dir, ".goxc.local.json"), filepath.Join(dir, ".goxc.json")}, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	//base.json ranks below both files which extend it
+	if settings.PackageVersion != "2.0" || settings.BuildConstraints != "linux" {
+		t.Errorf("Unexpected precedence: %+v", settings)
+	}
+}
@@ -0,0 +1,276 @@
+package config
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/openxo/goxc/core"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FORMAT_JSON = "json"
+	FORMAT_YAML = "yaml"
+	FORMAT_TOML = "toml"
+)
+
+// Config file formats (0.11.x)
+// YAML & TOML config files are converted to json as soon as they're read, so that they're merged & validated exactly like json files.
+// A config file's format is determined by its extension. Where a '.goxc.json' file doesn't exist, its '.yaml', '.yml' or '.toml' equivalent is used instead.
+var CONFIG_FILE_EXTS = []string{".json", ".yaml", ".yml", ".toml"}
+
+func configFormat(fileName string) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".yaml", ".yml":
+		return FORMAT_YAML
+	case ".toml":
+		return FORMAT_TOML
+	}
+	return FORMAT_JSON
+}
+
+// Given the name of a json config file, returns the name of whichever config file exists (json is preferred).
+// Returns the json name if none exist.
+func findConfigFile(jsonFile string) string {
+	base := strings.TrimSuffix(jsonFile, filepath.Ext(jsonFile))
+	found := []string{}
+	for _, ext := range CONFIG_FILE_EXTS {
+		if _, err := os.Stat(base + ext); err == nil {
+			found = append(found, base+ext)
+		}
+	}
+	if len(found) == 0 {
+		return jsonFile
+	}
+	if len(found) > 1 {
+		core.Warnf("(%s): ignoring %s", found[0], strings.Join(found[1:], ", "))
+	}
+	return found[0]
+}
+
+// Converts a yaml or toml config file to json. Json is returned as-is.
+func configToJson(raw []byte, fileName string) ([]byte, error) {
+	var v interface{}
+	switch configFormat(fileName) {
+	case FORMAT_YAML:
+		err := yaml.Unmarshal(raw, &v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid yaml: %v", err)
+		}
+		if v == nil {
+			//empty file
+			v = map[string]interface{}{}
+		}
+	case FORMAT_TOML:
+		m := map[string]interface{}{}
+		_, err := toml.Decode(string(raw), &m)
+		if err != nil {
+			return nil, fmt.Errorf("invalid toml: %v", err)
+		}
+		v = m
+	default:
+		return raw, nil
+	}
+	return json.MarshalIndent(stringKeys(v), "", "\t")
+}
+
+// yaml allows non-string keys (e.g. 'yes' or '1'), which json doesn't
+func stringKeys(v interface{}) interface{} {
+	switch typedV := v.(type) {
+	case map[string]interface{}:
+		for k, item := range typedV {
+			typedV[k] = stringKeys(item)
+		}
+	case map[interface{}]interface{}:
+		ret := map[string]interface{}{}
+		for k, item := range typedV {
+			ret[fmt.Sprintf("%v", k)] = stringKeys(item)
+		}
+		return ret
+	case []interface{}:
+		for i, item := range typedV {
+			typedV[i] = stringKeys(item)
+		}
+	case []map[string]interface{}:
+		//toml arrays of tables
+		ret := []interface{}{}
+		for _, item := range typedV {
+			ret = append(ret, stringKeys(item))
+		}
+		return ret
+	}
+	return v
+}
+
+// Converts json (as written by writeJsonFile) to the format of the given config file.
+// 'existing' is the file's current content (nil if it doesn't exist yet).
+// For yaml, changes are merged into the existing document, so that its comments & order are kept. Toml comments can't be kept.
+func jsonToConfig(data []byte, fileName string, existing []byte) ([]byte, error) {
+	switch configFormat(fileName) {
+	case FORMAT_YAML:
+		//json is yaml, so parse it as yaml (keeping the order), then re-format it in block style
+		var doc yaml.Node
+		err := yaml.Unmarshal(data, &doc)
+		if err != nil {
+			return nil, err
+		}
+		blockStyle(&doc)
+		var existingDoc yaml.Node
+		if len(existing) > 0 && yaml.Unmarshal(existing, &existingDoc) == nil && len(existingDoc.Content) > 0 {
+			mergeYamlNode(&existingDoc, &doc)
+			doc = existingDoc
+		}
+		buf := &bytes.Buffer{}
+		enc := yaml.NewEncoder(buf)
+		enc.SetIndent(2)
+		err = enc.Encode(&doc)
+		if err != nil {
+			return nil, err
+		}
+		err = enc.Close()
+		return buf.Bytes(), err
+	case FORMAT_TOML:
+		if hasTomlComments(existing) {
+			core.Warnf("(%s): comments are not kept when writing toml config", fileName)
+		}
+		d := json.NewDecoder(bytes.NewReader(data))
+		//avoid writing whole numbers as floats
+		d.UseNumber()
+		var m map[string]interface{}
+		err := d.Decode(&m)
+		if err != nil {
+			return nil, err
+		}
+		buf := &bytes.Buffer{}
+		enc := toml.NewEncoder(buf)
+		enc.Indent = ""
+		err = enc.Encode(tomlValues(m))
+		return buf.Bytes(), err
+	}
+	return data, nil
+}
+
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, child := range n.Content {
+		blockStyle(child)
+	}
+}
+
+// Updates an existing yaml node to match 'updated', keeping the existing comments (& the style of unchanged values).
+// Mapping keys keep their existing order; keys which are no longer set are removed, and new keys are added at the end.
+func mergeYamlNode(existing, updated *yaml.Node) {
+	switch {
+	case existing.Kind == yaml.DocumentNode && updated.Kind == yaml.DocumentNode && len(updated.Content) > 0:
+		mergeYamlNode(existing.Content[0], updated.Content[0])
+		return
+	case existing.Kind == yaml.MappingNode && updated.Kind == yaml.MappingNode:
+		updatedValues := map[string]*yaml.Node{}
+		for i := 0; i+1 < len(updated.Content); i += 2 {
+			updatedValues[updated.Content[i].Value] = updated.Content[i+1]
+		}
+		content := []*yaml.Node{}
+		merged := map[string]bool{}
+		//the comments above a removed key are moved to the next key
+		headComments := []string{}
+		for i := 0; i+1 < len(existing.Content); i += 2 {
+			key := existing.Content[i].Value
+			updatedValue, keyExists := updatedValues[key]
+			if !keyExists || merged[key] {
+				if existing.Content[i].HeadComment != "" {
+					headComments = append(headComments, existing.Content[i].HeadComment)
+				}
+				continue
+			}
+			mergeYamlNode(existing.Content[i+1], updatedValue)
+			if len(headComments) > 0 {
+				existing.Content[i].HeadComment = strings.TrimSpace(strings.Join(append(headComments, existing.Content[i].HeadComment), "\n"))
+				headComments = []string{}
+			}
+			content = append(content, existing.Content[i], existing.Content[i+1])
+			merged[key] = true
+		}
+		for i := 0; i+1 < len(updated.Content); i += 2 {
+			if !merged[updated.Content[i].Value] {
+				if len(headComments) > 0 {
+					updated.Content[i].HeadComment = strings.Join(headComments, "\n")
+					headComments = []string{}
+				}
+				content = append(content, updated.Content[i], updated.Content[i+1])
+			}
+		}
+		existing.Content = content
+		return
+	case existing.Kind == yaml.SequenceNode && updated.Kind == yaml.SequenceNode:
+		for i, item := range updated.Content {
+			if i < len(existing.Content) {
+				mergeYamlNode(existing.Content[i], item)
+			} else {
+				existing.Content = append(existing.Content, item)
+			}
+		}
+		existing.Content = existing.Content[:len(updated.Content)]
+		return
+	case existing.Kind == yaml.ScalarNode && updated.Kind == yaml.ScalarNode && existing.Value == updated.Value && existing.ShortTag() == updated.ShortTag():
+		//unchanged
+		return
+	}
+	headComment, lineComment, footComment := existing.HeadComment, existing.LineComment, existing.FootComment
+	*existing = *updated
+	existing.HeadComment, existing.LineComment, existing.FootComment = headComment, lineComment, footComment
+}
+
+// whether a toml file has any comment lines
+func hasTomlComments(raw []byte) bool {
+	for _, line := range strings.Split(string(raw), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			return true
+		}
+	}
+	return false
+}
+
+// json numbers to ints or floats. Nulls are dropped (toml has no null)
+func tomlValues(v interface{}) interface{} {
+	switch typedV := v.(type) {
+	case json.Number:
+		if i, err := typedV.Int64(); err == nil {
+			return i
+		}
+		f, _ := typedV.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range typedV {
+			if item == nil {
+				delete(typedV, k)
+			} else {
+				typedV[k] = tomlValues(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range typedV {
+			typedV[i] = tomlValues(item)
+		}
+	}
+	return v
+}
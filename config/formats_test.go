@@ -0,0 +1,129 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const formatsTestJson = `{"ConfigVersion": "0.9", "PackageVersion": "1.2.3", "Parallelism": 2, "Tasks": ["xc", "archive"],
+	"TaskSettings": {"exec": {"commands": ["go generate ./...", {"command": "upx {{.BinPath}}", "scope": "binary"}]}},
+	"PlatformOverrides": {"linux,arm": {"Env": ["CGO_ENABLED=0"]}}}`
+
+const formatsTestYaml = `# comments are allowed
+ConfigVersion: "0.9"
+PackageVersion: 1.2.3
+Parallelism: 2
+Tasks: [xc, archive]
+TaskSettings:
+  exec:
+    commands:
+      - go generate ./...
+      - command: upx {{.BinPath}}
+        scope: binary
+PlatformOverrides:
+  linux,arm:
+    Env: [CGO_ENABLED=0]
+`
+
+const formatsTestToml = `# comments are allowed
+ConfigVersion = "0.9"
+PackageVersion = "1.2.3"
+Parallelism = 2
+Tasks = ["xc", "archive"]
+
+[TaskSettings.exec]
+commands = ["go generate ./...", {command = "upx {{.BinPath}}", scope = "binary"}]
+
+[PlatformOverrides."linux,arm"]
+Env = ["CGO_ENABLED=0"]
+`
+
+func TestConfigFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-formats")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+	load := func(name, content string) Settings {
+		fileName := filepath.Join(dir, name)
+		ioutil.WriteFile(fileName, []byte(content), 0644)
+		defer os.Remove(fileName)
+		//found via its .json name
+		settings, err := LoadJsonConfigs(dir, []string{filepath.Join(dir, ".goxc.json")}, false)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		settings.origins = nil
+		return settings
+	}
+	expected := load(".goxc.json", formatsTestJson)
+	for name, content := range map[string]string{".goxc.yaml": formatsTestYaml, ".goxc.yml": formatsTestYaml, ".goxc.toml": formatsTestToml} {
+		actual := load(name, content)
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("%s: expected %+v, got %+v", name, expected, actual)
+		}
+	}
+}
+
+func TestWriteConfigFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-formats")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{".goxc.yaml": formatsTestYaml, ".goxc.toml": formatsTestToml} {
+		fileName := filepath.Join(dir, name)
+		ioutil.WriteFile(fileName, []byte(content), 0644)
+		settings, err := LoadJsonConfigsWithoutExtends(dir, []string{filepath.Join(dir, ".goxc.json")}, false)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		settings.PackageVersion = "1.2.4"
+		err = WriteJsonConfig(dir, settings, "", false)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err = os.Stat(filepath.Join(dir, ".goxc.json")); err == nil {
+			t.Errorf("%s: expected the existing file to be written, not .goxc.json", name)
+		}
+		written, _ := ioutil.ReadFile(fileName)
+		if strings.HasPrefix(strings.TrimSpace(string(written)), "{") {
+			t.Errorf("%s: written as json: %s", name, written)
+		}
+		reloaded, err := LoadJsonConfigsWithoutExtends(dir, []string{filepath.Join(dir, ".goxc.json")}, false)
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", name, err, written)
+		}
+		if name == ".goxc.yaml" && !strings.HasPrefix(string(written), "# comments are allowed\n") {
+			t.Errorf("%s: comments not kept: %s", name, written)
+		}
+		if reloaded.PackageVersion != "1.2.4" || reloaded.Parallelism != 2 || !reflect.DeepEqual(reloaded.GetTaskSetting("exec", "commands"), settings.GetTaskSetting("exec", "commands")) {
+			t.Errorf("%s: unexpected settings after writing: %+v\n%s", name, reloaded, written)
+		}
+		os.Remove(fileName)
+	}
+}
+
+func TestMergeYamlNode(t *testing.T) {
+	existing := `# build settings
+PackageVersion: 1.2.3 # bumped by hand
+Tasks: [xc, archive]
+Parallelism: 2
+`
+	written, err := jsonToConfig([]byte(`{"PackageVersion": "1.2.4", "Tasks": ["xc", "archive"], "Verbosity": "v"}`), ".goxc.yaml", []byte(existing))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	expected := `# build settings
+PackageVersion: 1.2.4 # bumped by hand
+Tasks: [xc, archive]
+Verbosity: v
+`
+	if string(written) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, written)
+	}
+}
@@ -0,0 +1,288 @@
+package config
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/openxo/goxc/core"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	SECRET_MASK = "****"
+	//shorter values aren't masked (they'd garble the output, and aren't much of a secret anyway)
+	secretMinLength = 4
+)
+
+// keys whose values are treated as secrets, whether or not they're interpolated
+var secretKeyNames = []string{"apikey", "api_key", "access_key", "_key", "private", "password", "passwd", "passphrase", "secret", "token", "credential"}
+
+// Interpolation (0.11.x)
+// String values in config files can contain ${NAME}, ${env:NAME}, ${env:NAME:-default} and ${file:/path/to/secret}.
+// ('$${' is a literal '${')
+// The original expressions are remembered by key path, so that writing config (-wc) writes them back instead of the expanded values.
+type interpolation struct {
+	Raw      string
+	Expanded string
+	IsSecret bool
+}
+
+var (
+	interpolationLock sync.Mutex
+	//by key path (e.g. TaskSettings.bintray.apikey)
+	interpolations = map[string]interpolation{}
+	secretValues   = map[string]bool{}
+)
+
+// Forgets the interpolations & secrets of any earlier load. Call at the start of each (top-level) load, so that key paths from one config aren't written back into another
+func resetInterpolations() {
+	interpolationLock.Lock()
+	defer interpolationLock.Unlock()
+	interpolations = map[string]interpolation{}
+	secretValues = map[string]bool{}
+}
+
+// Expands ${...} expressions in all string values. Relative ${file:...} paths are relative to configDir.
+func interpolateSettingsMap(settingsMap map[string]interface{}, configDir string) (map[string]interface{}, error) {
+	v, err := interpolateValue(settingsMap, "", configDir, false)
+	if err != nil {
+		return settingsMap, err
+	}
+	return v.(map[string]interface{}), nil
+}
+
+func interpolateValue(v interface{}, path, configDir string, isSecretKey bool) (interface{}, error) {
+	switch typedV := v.(type) {
+	case string:
+		expanded, fromFile, err := expandString(typedV, configDir)
+		if err != nil {
+			return v, fmt.Errorf("'%s': %v", path, err)
+		}
+		isSecret := isSecretKey || fromFile
+		interpolationLock.Lock()
+		defer interpolationLock.Unlock()
+		if isSecret && len(expanded) >= secretMinLength {
+			secretValues[expanded] = true
+		}
+		if expanded != typedV {
+			if _, keyExists := interpolations[path]; !keyExists {
+				//first (most important) file wins
+				interpolations[path] = interpolation{Raw: typedV, Expanded: expanded, IsSecret: isSecret && len(expanded) >= secretMinLength}
+			}
+		}
+		return expanded, nil
+	case []interface{}:
+		ret := make([]interface{}, len(typedV))
+		for i, item := range typedV {
+			expanded, err := interpolateValue(item, fmt.Sprintf("%s[%d]", path, i), configDir, isSecretKey)
+			if err != nil {
+				return v, err
+			}
+			ret[i] = expanded
+		}
+		return ret, nil
+	case map[string]interface{}:
+		ret := map[string]interface{}{}
+		for k, item := range typedV {
+			expanded, err := interpolateValue(item, joinSchemaPath(path, k), configDir, isSecretKey || isSecretKeyName(k))
+			if err != nil {
+				return v, err
+			}
+			ret[k] = expanded
+		}
+		return ret, nil
+	}
+	return v, nil
+}
+
+func isSecretKeyName(k string) bool {
+	lower := strings.ToLower(k)
+	for _, name := range secretKeyNames {
+		if strings.Contains(lower, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expands any ${...} expressions. Also returns whether any value came from a file (which makes it a secret).
+func expandString(s, configDir string) (string, bool, error) {
+	if !strings.Contains(s, "${") {
+		return s, false, nil
+	}
+	ret := ""
+	fromFile := false
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			return ret + s, fromFile, nil
+		}
+		if start > 0 && s[start-1] == '$' {
+			//escaped
+			ret += s[:start-1] + "${"
+			s = s[start+2:]
+			continue
+		}
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			return s, false, fmt.Errorf("unterminated '${' in '%s'", s)
+		}
+		expression := s[start+2 : start+end]
+		var value string
+		var err error
+		if strings.HasPrefix(expression, "file:") {
+			value, err = readSecretFile(strings.TrimPrefix(expression, "file:"), configDir)
+			if err != nil {
+				return s, false, err
+			}
+			fromFile = true
+		} else {
+			value = expandEnv(strings.TrimPrefix(expression, "env:"))
+		}
+		ret += s[:start] + value
+		s = s[start+end+1:]
+	}
+}
+
+// NAME or NAME:-default
+func expandEnv(expression string) string {
+	name := expression
+	defaultValue := ""
+	hasDefault := false
+	if i := strings.Index(expression, ":-"); i >= 0 {
+		name = expression[:i]
+		defaultValue = expression[i+2:]
+		hasDefault = true
+	}
+	value, isSet := os.LookupEnv(name)
+	if value == "" && hasDefault {
+		return defaultValue
+	}
+	if !isSet {
+		core.Warnf("Environment variable '%s' is not set (use ${env:%s:-default} to give a default)", name, name)
+	}
+	return value
+}
+
+// file contents, without trailing newlines
+func readSecretFile(fileName, configDir string) (string, error) {
+	if !filepath.IsAbs(fileName) {
+		fileName = filepath.Join(configDir, fileName)
+	}
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Replaces any secrets (values of secret-looking keys, and values read from files) with '****'. Use for logging settings.
+func MaskSecrets(s string) string {
+	interpolationLock.Lock()
+	defer interpolationLock.Unlock()
+	secrets := []string{}
+	for secret := range secretValues {
+		secrets = append(secrets, secret)
+	}
+	//longest first, in case one contains another
+	sort.Sort(longestFirst(secrets))
+	for _, secret := range secrets {
+		s = strings.Replace(s, secret, SECRET_MASK, -1)
+	}
+	return s
+}
+
+// Masks the values of secret-looking environment variables (e.g. BINTRAY_APIKEY), and any secrets from config. Use for logging environments.
+func MaskSecretEnv(env []string) []string {
+	ret := []string{}
+	for _, kv := range env {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 && parts[1] != "" && isSecretKeyName(parts[0]) {
+			kv = parts[0] + "=" + SECRET_MASK
+		}
+		ret = append(ret, MaskSecrets(kv))
+	}
+	return ret
+}
+
+// Puts back the original ${...} expressions, wherever the expanded value is unchanged.
+// Returns an error if an interpolated secret would still be written.
+// The json is re-written in the same key order, without escaping '<', '>' & '&' (see writeOrderedJson).
+func restoreInterpolations(data []byte) ([]byte, error) {
+	interpolationLock.Lock()
+	defer interpolationLock.Unlock()
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&v)
+	if err != nil {
+		return data, err
+	}
+	//keep the order of the settings (& don't escape '<', '>' & '&')
+	keyOrders, err := jsonKeyOrders(data)
+	if err != nil {
+		return data, err
+	}
+	v = restoreValue(v, "")
+	buf := &bytes.Buffer{}
+	err = writeOrderedJson(buf, v, "", "", keyOrders)
+	if err != nil {
+		return data, err
+	}
+	data = buf.Bytes()
+	for path, i := range interpolations {
+		//as encoded in json
+		expandedJson := &bytes.Buffer{}
+		writeJsonScalar(expandedJson, i.Expanded)
+		if i.IsSecret && strings.Contains(string(data), strings.Trim(expandedJson.String(), "\"")) {
+			return nil, fmt.Errorf("refusing to write the secret value of '%s' (%s) to config", path, i.Raw)
+		}
+	}
+	return data, nil
+}
+
+// call with lock held
+func restoreValue(v interface{}, path string) interface{} {
+	switch typedV := v.(type) {
+	case string:
+		if i, keyExists := interpolations[path]; keyExists && i.Expanded == typedV {
+			return i.Raw
+		}
+	case []interface{}:
+		for index, item := range typedV {
+			typedV[index] = restoreValue(item, fmt.Sprintf("%s[%d]", path, index))
+		}
+	case map[string]interface{}:
+		for k, item := range typedV {
+			typedV[k] = restoreValue(item, joinSchemaPath(path, k))
+		}
+	}
+	return v
+}
+
+type longestFirst []string
+
+func (a longestFirst) Len() int           { return len(a) }
+func (a longestFirst) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a longestFirst) Less(i, j int) bool { return len(a[i]) > len(a[j]) }
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInterpolateSettingsMap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-interpolate")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "key.txt"), []byte("s3cr3t-key\n"), 0600)
+	os.Setenv("GOXC_TEST_USER", "someone")
+	defer os.Unsetenv("GOXC_TEST_USER")
+
+	m := map[string]interface{}{
+		"PackageVersion": "${env:GOXC_TEST_UNSET:-1.2.3}",
+		"TaskSettings": map[string]interface{}{
+			"bintray": map[string]interface{}{
+				"subject": "${GOXC_TEST_USER}",
+				"apikey":  "${file:key.txt}",
+				"package": "$${literal}"}}}
+	m, err = interpolateSettingsMap(m, dir)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	settings, err := loadSettingsSection(m)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if settings.PackageVersion != "1.2.3" {
+		t.Errorf("Default not applied: '%s'", settings.PackageVersion)
+	}
+	if settings.GetTaskSettingString("bintray", "subject") != "someone" {
+		t.Errorf("Env var not expanded: %v", settings.TaskSettings)
+	}
+	if settings.GetTaskSettingString("bintray", "apikey") != "s3cr3t-key" {
+		t.Errorf("File not read: %v", settings.TaskSettings)
+	}
+	if settings.GetTaskSettingString("bintray", "package") != "${literal}" {
+		t.Errorf("Escape not handled: %v", settings.TaskSettings)
+	}
+	if masked := MaskSecrets("apikey:s3cr3t-key"); masked != "apikey:"+SECRET_MASK {
+		t.Errorf("Secret not masked: %s", masked)
+	}
+	data, err := restoreInterpolations([]byte(`{"TaskSettings":{"bintray":{"apikey":"s3cr3t-key","subject":"someone"}}}`))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if strings.Contains(string(data), "s3cr3t") || !strings.Contains(string(data), "${GOXC_TEST_USER}") {
+		t.Errorf("Expressions not restored: %s", data)
+	}
+	_, err = restoreInterpolations([]byte(`{"Tasks":["s3cr3t-key"]}`))
+	if err == nil {
+		t.Errorf("Expected an error, writing a secret")
+	}
+}
+
+func TestMaskSecretEnv(t *testing.T) {
+	env := []string{"GOOS=linux", "ANTHROPIC_API_KEY=sk-123456", "AWS_SECRET_ACCESS_KEY=abcdefgh", "SIGNING_PRIVATE=abcdefgh", "GOARM=7"}
+	expected := []string{"GOOS=linux", "ANTHROPIC_API_KEY=" + SECRET_MASK, "AWS_SECRET_ACCESS_KEY=" + SECRET_MASK, "SIGNING_PRIVATE=" + SECRET_MASK, "GOARM=7"}
+	if masked := MaskSecretEnv(env); !reflect.DeepEqual(masked, expected) {
+		t.Errorf("Expected %v, got %v", expected, masked)
+	}
+}
+
+func TestWriteConfigWithInterpolations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-interpolate")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("GOXC_TEST_USER", "someone")
+	defer os.Unsetenv("GOXC_TEST_USER")
+	//an earlier load, of a different file
+	other := filepath.Join(dir, "other.goxc.json")
+	ioutil.WriteFile(other, []byte(`{"ConfigVersion": "0.9", "PackageVersion": "${GOXC_TEST_USER}"}`), 0644)
+	if _, err = LoadJsonConfigs(dir, []string{other}, false); err != nil {
+		t.Fatalf("%v", err)
+	}
+	jsonFile := filepath.Join(dir, ".goxc.json")
+	ioutil.WriteFile(jsonFile, []byte(`{"ConfigVersion": "0.9", "PackageVersion": "someone", "TaskSettings": {"pkg-build": {"metadata": {"maintainer": "Joe <joe@x.com>"}, "other": "${GOXC_TEST_USER}"}}}`), 0644)
+	settings, err := LoadJsonConfigsWithoutExtends(dir, []string{jsonFile}, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = WriteJsonConfig(dir, settings, "", false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	written, _ := ioutil.ReadFile(jsonFile)
+	if !strings.Contains(string(written), `"PackageVersion": "someone"`) || !strings.Contains(string(written), `"other": "${GOXC_TEST_USER}"`) {
+		t.Errorf("Unexpected interpolations: %s", written)
+	}
+	if !strings.Contains(string(written), "Joe <joe@x.com>") {
+		t.Errorf("Escaped: %s", written)
+	}
+	//in the order of the Settings struct, not sorted
+	if strings.Index(string(written), "ConfigVersion") < strings.Index(string(written), "TaskSettings") {
+		t.Errorf("Reordered: %s", written)
+	}
+}
@@ -19,6 +19,7 @@ package config
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/openxo/goxc/core"
 	"github.com/openxo/goxc/typeutils"
 	"io/ioutil"
@@ -51,14 +52,31 @@ func LoadJsonConfigOverrideable(dir string, configName string, isRead, isWriteLo
 			configs = []string{configName + core.GOXC_FILE_EXT}
 		}
 	}
+	if !isRead {
+		//0.11.x writing: don't flatten any parent configs into this one
+		return LoadJsonConfigsWithoutExtends(dir, configs, verbose)
+	}
 	return LoadJsonConfigs(dir, configs, verbose)
 }
 
 func LoadJsonConfigs(dir string, configs []string, verbose bool) (Settings, error) {
+	return loadJsonConfigs(configs, true, verbose)
+}
+
+// 0.11.x Loads config files without their parents ("Extends"), e.g. for writing back to the same file.
+func LoadJsonConfigsWithoutExtends(dir string, configs []string, verbose bool) (Settings, error) {
+	return loadJsonConfigs(configs, false, verbose)
+}
+
+func loadJsonConfigs(configs []string, isFollowExtends, verbose bool) (Settings, error) {
+	//0.11.x interpolations are remembered per load
+	resetInterpolations()
 	//most-important first
-	mergedSettingsMap := map[string]interface{}{}
+	loaded := []loadedConfig{}
 	for _, jsonFile := range configs {
-		settingsMap, err := loadJsonFileAsMap(jsonFile, verbose)
+		//0.11.x or its yaml/toml equivalent
+		jsonFile = findConfigFile(jsonFile)
+		err := loadJsonConfigAndParents(jsonFile, []string{}, isFollowExtends, &loaded, verbose)
 		if err != nil {
 			if os.IsNotExist(err) {
 				//continue onto next file
@@ -66,34 +84,73 @@ func LoadJsonConfigs(dir string, configs []string, verbose bool) (Settings, erro
 				//parse error. Stop right there.
 				return Settings{}, err
 			}
-		} else {
-			mergedSettingsMap = typeutils.MergeMaps(mergedSettingsMap, settingsMap)
 		}
 	}
-	return loadSettingsSection(mergedSettingsMap)
+	//0.11.x a shared parent ranks below all the files which extend it
+	loaded = lowestRanked(loaded)
+	mergedSettingsMap := map[string]interface{}{}
+	for _, loadedFile := range loaded {
+		mergedSettingsMap = typeutils.MergeMaps(mergedSettingsMap, loadedFile.SettingsMap)
+	}
+	//0.11.x validate against the schema, once plugins declared in any file are known
+	pluginNames := declaredPluginNames(mergedSettingsMap)
+	for _, loadedFile := range loaded {
+		errs := validateSchema(loadedFile.RawJson, loadedFile.File, pluginNames)
+		if len(errs) > 0 {
+			return Settings{}, errs[0]
+		}
+	}
+	settings, err := loadSettingsSection(mergedSettingsMap)
+	if err != nil {
+		return settings, err
+	}
+	//0.11.x for -explain-config
+	settings.recordFileOrigins(loaded)
+	return settings, nil
 }
 
 //0.5.6 provide more detail about errors (syntax errors for now)
+//0.11.x schema errors & warnings too
 func printErrorDetails(rawJson []byte, err error) {
 	switch typedErr := err.(type) {
 	case *json.SyntaxError:
-		lineNumber := 1
-		colNumber := 0
-		for i, b := range rawJson {
-			if int64(i) == typedErr.Offset {
-				log.Printf("JSON syntax error on line %d, column %d", lineNumber, colNumber)
-				return
-			}
-			if b == '\n' {
-				lineNumber = lineNumber + 1
-				colNumber = 0
-			} else {
-				colNumber = colNumber + 1
-			}
+		lineNumber, colNumber, found := lineAndColumn(rawJson, typedErr.Offset)
+		if found {
+			log.Printf("JSON syntax error on line %d, column %d", lineNumber, colNumber)
+		}
+	case *SchemaError:
+		location := typedErr.File
+		lineNumber, colNumber, found := lineAndColumn(rawJson, typedErr.Offset)
+		//yaml & toml files are validated after conversion to json, so line numbers wouldn't match
+		if found && configFormat(typedErr.File) == FORMAT_JSON {
+			location = fmt.Sprintf("%s, line %d, column %d", typedErr.File, lineNumber, colNumber)
+		}
+		if typedErr.IsWarning {
+			core.Warnf("(%s): '%s' %s", location, typedErr.Path, typedErr.Message)
+		} else {
+			log.Printf("ERROR (%s): '%s' %s", location, typedErr.Path, typedErr.Message)
 		}
 	}
 }
 
+// line & column of a byte offset
+func lineAndColumn(rawJson []byte, offset int64) (int, int, bool) {
+	lineNumber := 1
+	colNumber := 0
+	for i, b := range rawJson {
+		if int64(i) == offset {
+			return lineNumber, colNumber, true
+		}
+		if b == '\n' {
+			lineNumber = lineNumber + 1
+			colNumber = 0
+		} else {
+			colNumber = colNumber + 1
+		}
+	}
+	return lineNumber, colNumber, false
+}
+
 func validateRawJson(rawJson []byte, fileName string) []error {
 	var f interface{}
 	err := json.Unmarshal(rawJson, &f)
@@ -145,7 +202,7 @@ func validateSettingsSection(settings map[string]interface{}, fileName string, r
 			if len(valArr) == 1 && valArr[0] == core.TASK_BUILD_TOOLCHAIN {
 				//build-toolchain hasn't changed. Continue.
 			} else {
-				msg := "task definitions have changed in version 0.5.0. Please refer to latest docs and update your config file to version 0.5.0 accordingly."
+				msg := "task definitions have changed in version 0.5.0. Please run 'goxc migrate-config', or refer to latest docs and update your config file accordingly."
 				log.Printf("ERROR (%s): %s", fileName, msg)
 				errs = append(errs, errors.New(msg))
 			}
@@ -174,17 +231,34 @@ func loadFile(jsonFile string, verbose bool) ([]byte, error) {
 }
 
 func loadJsonFileAsMap(jsonFile string, verbose bool) (map[string]interface{}, error) {
-	var f map[string]interface{}
+	f, _, err := loadJsonFileAsMapAndRaw(jsonFile, verbose)
+	return f, err
+}
+
+// 0.11.x also returns the raw json, for schema validation
+// 0.11.x yaml & toml files are converted to json first
+func loadJsonFileAsMapAndRaw(jsonFile string, verbose bool) (map[string]interface{}, []byte, error) {
 	rawJson, err := loadFile(jsonFile, verbose)
 	if err != nil {
-		return f, err
+		return nil, rawJson, err
 	}
+	rawJson, err = configToJson(rawJson, jsonFile)
+	if err != nil {
+		log.Printf("ERROR (%s): %v", jsonFile, err)
+		return nil, rawJson, err
+	}
+	f, err := parseJsonFileAsMap(rawJson, jsonFile)
+	return f, rawJson, err
+}
+
+func parseJsonFileAsMap(rawJson []byte, jsonFile string) (map[string]interface{}, error) {
+	var f map[string]interface{}
 	errs := validateRawJson(rawJson, jsonFile)
 	if errs != nil && len(errs) > 0 {
 		return f, errs[0]
 	}
 	//TODO: super-verbose option for logging file content? log.Printf("%s\n", string(file))
-	err = json.Unmarshal(rawJson, &f)
+	err := json.Unmarshal(rawJson, &f)
 	if err != nil {
 		log.Printf("ERROR (%s): invalid json!", jsonFile)
 		printErrorDetails(rawJson, err)
@@ -282,6 +356,28 @@ func loadSettingsSection(settingsSection map[string]interface{}) (settings Setti
 			settings.BuildName, err = typeutils.ToString(v, k)
 		case "Verbosity":
 			settings.Verbosity, err = typeutils.ToString(v, k)
+		case "Parallelism":
+			var fp float64
+			fp, err = typeutils.ToFloat64(v, k)
+			if err == nil {
+				settings.Parallelism = int(fp)
+			}
+		case "FirstClassOnly":
+			settings.FirstClassOnly, err = typeutils.ToBool(v, k)
+		case "KeepGoing":
+			settings.KeepGoing, err = typeutils.ToBool(v, k)
+		case "Aliases":
+			settings.Aliases, err = typeutils.ToMapStringStringSlice(v, k)
+		case "Plugins":
+			settings.Plugins, err = pluginsFromMap(v, k)
+		case "Extends":
+			settings.Extends, err = extendsFromValue(v, k)
+		case "PlatformOverrides":
+			settings.PlatformOverrides, err = platformOverridesFromMap(v, k)
+		case "Cgo":
+			settings.Cgo, err = cgoSettingsFromMap(v, k)
+		case "Binaries":
+			settings.Binaries, err = binariesFromMap(v, k)
 		case "TaskSettings":
 			settings.TaskSettings, err = typeutils.ToMapStringMapStringInterface(v, k)
 		case "FormatVersion":
@@ -297,7 +393,7 @@ func loadSettingsSection(settingsSection map[string]interface{}) (settings Setti
 				}
 			}
 		default:
-			log.Printf("Warning!! Unrecognised Setting '%s' (value %v)", k, v)
+			//0.11.x unrecognised settings are reported by schema validation (with suggestions)
 		}
 		if err != nil {
 			return settings, err
@@ -312,11 +408,12 @@ func WriteJsonConfig(dir string, settings Settings, configName string, isLocal b
 	if settings.BuildSettings != nil && bs.Equals(*settings.BuildSettings) {
 		settings.BuildSettings = nil
 	}
+	//0.11.x keep the format of an existing yaml or toml file
 	if isLocal {
-		jsonFile := filepath.Join(dir, configName+core.GOXC_LOCAL_FILE_EXT)
+		jsonFile := findConfigFile(filepath.Join(dir, configName+core.GOXC_LOCAL_FILE_EXT))
 		return writeJsonFile(settings, jsonFile)
 	}
-	jsonFile := filepath.Join(dir, configName+core.GOXC_FILE_EXT)
+	jsonFile := findConfigFile(filepath.Join(dir, configName+core.GOXC_FILE_EXT))
 	return writeJsonFile(settings, jsonFile)
 }
 
@@ -326,10 +423,25 @@ func writeJsonFile(settings Settings, jsonFile string) error {
 		log.Printf("Could NOT marshal json")
 		return err
 	}
+	//0.11.x write ${...} expressions rather than their values (especially secrets)
+	data, err = restoreInterpolations(data)
+	if err != nil {
+		log.Printf("Could NOT write config: %v", err)
+		return err
+	}
 	//0.6 StripEmpties no longer required (use omitempty tag instead)
+	existing, err := ioutil.ReadFile(jsonFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	data, err = jsonToConfig(data, jsonFile, existing)
+	if err != nil {
+		log.Printf("Could NOT convert config to %s: %v", configFormat(jsonFile), err)
+		return err
+	}
 
 	log.Printf("Writing file %s", jsonFile)
-	return ioutil.WriteFile(jsonFile, data, 0644)
+	return core.WriteFile(jsonFile, data, 0644)
 }
 
 //use json from string
@@ -340,7 +452,7 @@ func readJson(js []byte) (Settings, error) {
 	if err != nil {
 		log.Printf("Error: %v", err)
 	}
-	log.Printf("Settings: %+v", settings)
+	log.Printf("Settings: %s", MaskSecrets(fmt.Sprintf("%+v", settings)))
 	return settings, err
 }
 
@@ -59,3 +59,22 @@ func TestLoadParentFile(t *testing.T) {
 	}
 }
 */
+
+func TestLoadAliases(t *testing.T) {
+	m := map[string]interface{}{
+		"Aliases": map[string]interface{}{
+			"release": []interface{}{"xc", "archive", "bintray"},
+		},
+	}
+	settings, err := loadSettingsSection(m)
+	if err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	if len(settings.Aliases["release"]) != 3 {
+		t.Fatalf("Alias not loaded: %v", settings.Aliases)
+	}
+	merged := Merge(Settings{Aliases: map[string][]string{"quick": []string{"xc"}}}, settings)
+	if len(merged.Aliases) != 2 {
+		t.Fatalf("Aliases not merged: %v", merged.Aliases)
+	}
+}
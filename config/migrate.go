@@ -0,0 +1,430 @@
+package config
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/openxo/goxc/core"
+	"github.com/openxo/goxc/typeutils"
+	"io"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const MIGRATE_BACKUP_EXT = ".bak"
+
+// Task names used before 0.5.0, and their replacements
+var OLD_TASK_NAMES = map[string]string{
+	"zip":           "archive-zip",
+	"archive-tgz":   "archive-tar-gz",
+	"tgz":           "archive-tar-gz",
+	"deb":           "pkg-build",
+	"downloadspage": "downloads-page",
+	"rm-bin":        "rmbin",
+}
+
+// Config migration (0.11.x)
+// Rewrites goxc config files in the given dir (*.goxc.json & *.goxc.local.json, or their yaml or toml equivalents) to the current ConfigVersion.
+// Each changed file is backed up (with a '.bak' extension), and a diff is written to 'out'.
+func MigrateConfigFiles(dir string, out io.Writer) error {
+	files := []string{}
+	for _, prefix := range []string{strings.TrimSuffix(core.GOXC_FILE_EXT, ".json"), strings.TrimSuffix(core.GOXC_LOCAL_FILE_EXT, ".json")} {
+		for _, ext := range CONFIG_FILE_EXTS {
+			matches, err := filepath.Glob(filepath.Join(dir, "*"+prefix+ext))
+			if err != nil {
+				return err
+			}
+			files = append(files, matches...)
+		}
+	}
+	sort.Strings(files)
+	if len(files) == 0 {
+		log.Printf("No config files found in %s", dir)
+	}
+	for _, configFile := range files {
+		err := MigrateConfigFile(configFile, out)
+		if err != nil {
+			return fmt.Errorf("%s: %v", configFile, err)
+		}
+	}
+	return nil
+}
+
+// Migrates one config file (json, yaml or toml)
+func MigrateConfigFile(configFile string, out io.Writer) error {
+	raw, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+	rawJson, err := configToJson(raw, configFile)
+	if err != nil {
+		return err
+	}
+	var m map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(rawJson))
+	//numbers are kept as written
+	decoder.UseNumber()
+	err = decoder.Decode(&m)
+	if err != nil {
+		log.Printf("ERROR (%s): invalid json!", configFile)
+		printErrorDetails(rawJson, err)
+		return err
+	}
+	changes, err := migrateSettingsMap(m)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		log.Printf("%s is up to date (ConfigVersion %s)", configFile, GOXC_CONFIG_VERSION)
+		return nil
+	}
+	keyOrders, err := jsonKeyOrders(rawJson)
+	if err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	err = writeOrderedJson(buf, m, "", "", keyOrders)
+	if err != nil {
+		return err
+	}
+	buf.WriteString("\n")
+	//yaml keeps its comments
+	migrated, err := jsonToConfig(buf.Bytes(), configFile, raw)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		log.Printf("%s: %s", configFile, change)
+	}
+	fmt.Fprintf(out, "--- %s\n+++ %s\n", configFile+MIGRATE_BACKUP_EXT, configFile)
+	for _, line := range lineDiff(strings.Split(string(raw), "\n"), strings.Split(string(migrated), "\n")) {
+		fmt.Fprintln(out, line)
+	}
+	err = core.WriteFile(configFile+MIGRATE_BACKUP_EXT, raw, 0644)
+	if err != nil {
+		return err
+	}
+	return core.WriteFile(configFile, migrated, 0644)
+}
+
+// Converts old settings to their current equivalents, in place. Returns a description of each change
+func migrateSettingsMap(m map[string]interface{}) ([]string, error) {
+	changes := []string{}
+	//until 0.9, settings were in a 'Settings' section
+	if s, keyExists := m["Settings"]; keyExists {
+		settingsSection, ok := s.(map[string]interface{})
+		if !ok {
+			return changes, fmt.Errorf("'Settings' should be a json object, not a %T", s)
+		}
+		delete(m, "Settings")
+		for k, v := range settingsSection {
+			if _, keyExists := m[k]; !keyExists {
+				m[k] = v
+			}
+		}
+		changes = append(changes, "moved the contents of 'Settings' to the top level")
+	}
+	configVersion := ""
+	if fv, keyExists := m["FormatVersion"]; keyExists {
+		configVersion, _ = fv.(string)
+		delete(m, "FormatVersion")
+		changes = append(changes, "renamed 'FormatVersion' to 'ConfigVersion'")
+	}
+	if cv, keyExists := m["ConfigVersion"]; keyExists {
+		configVersion, _ = cv.(string)
+	}
+	if r, keyExists := m["Resources"]; keyExists {
+		resources, ok := r.(map[string]interface{})
+		if !ok {
+			return changes, fmt.Errorf("'Resources' should be a json object, not a %T", r)
+		}
+		for k, v := range resources {
+			if _, keyExists := m["Resources"+k]; !keyExists {
+				m["Resources"+k] = v
+			}
+		}
+		delete(m, "Resources")
+		changes = append(changes, "replaced 'Resources' with 'ResourcesInclude' & 'ResourcesExclude'")
+	}
+	if c, keyExists := m["Codesign"]; keyExists {
+		if id, ok := c.(string); ok && id != "" {
+			setTaskSettingInMap(m, "codesign", "id", id)
+		}
+		delete(m, "Codesign")
+		changes = append(changes, "replaced 'Codesign' with 'TaskSettings.codesign.id'")
+	}
+	if at, keyExists := m["ArtifactTypes"]; keyExists {
+		artifactTypes, ok := at.([]interface{})
+		if !ok {
+			return changes, fmt.Errorf("'ArtifactTypes' should be a json array, not a %T", at)
+		}
+		isZip, isBin := false, false
+		for _, artifactType := range artifactTypes {
+			switch artifactType {
+			case "zip":
+				isZip = true
+			case "bin":
+				isBin = true
+			}
+		}
+		//by default, binaries are archived and then removed
+		exclusions := []string{}
+		if !isZip {
+			exclusions = append(exclusions, "archive")
+		}
+		if isBin {
+			exclusions = append(exclusions, "rmbin")
+		}
+		for _, exclusion := range exclusions {
+			m["TasksExclude"] = appendToList(m["TasksExclude"], exclusion)
+		}
+		delete(m, "ArtifactTypes")
+		changes = append(changes, fmt.Sprintf("replaced 'ArtifactTypes' %v with 'TasksExclude' %v", artifactTypes, exclusions))
+	}
+	//as per validateSettingsSection
+	if -1 == typeutils.StringSlicePos(GOXC_CONFIG_SUPPORTED, configVersion) {
+		for _, k := range []string{"Tasks", "TasksExclude", "TasksAppend", "TasksPrepend"} {
+			if taskNames, ok := m[k].([]interface{}); ok {
+				for i, taskName := range taskNames {
+					if newName, keyExists := OLD_TASK_NAMES[fmt.Sprintf("%v", taskName)]; keyExists {
+						taskNames[i] = newName
+						changes = append(changes, fmt.Sprintf("renamed task '%v' to '%s' in '%s'", taskName, newName, k))
+					}
+				}
+			}
+		}
+	}
+	if configVersion != GOXC_CONFIG_VERSION {
+		m["ConfigVersion"] = GOXC_CONFIG_VERSION
+		changes = append(changes, fmt.Sprintf("set 'ConfigVersion' to %s (was '%s')", GOXC_CONFIG_VERSION, configVersion))
+	}
+	return changes, nil
+}
+
+func setTaskSettingInMap(m map[string]interface{}, taskName, settingName string, value interface{}) {
+	taskSettings, ok := m["TaskSettings"].(map[string]interface{})
+	if !ok {
+		taskSettings = map[string]interface{}{}
+		m["TaskSettings"] = taskSettings
+	}
+	taskSetting, ok := taskSettings[taskName].(map[string]interface{})
+	if !ok {
+		taskSetting = map[string]interface{}{}
+		taskSettings[taskName] = taskSetting
+	}
+	if _, keyExists := taskSetting[settingName]; !keyExists {
+		taskSetting[settingName] = value
+	}
+}
+
+func appendToList(list interface{}, item string) []interface{} {
+	items, _ := list.([]interface{})
+	for _, existing := range items {
+		if existing == item {
+			return items
+		}
+	}
+	return append(items, item)
+}
+
+// Keys which migrateSettingsMap replaces, and the keys which take their place (in the same position)
+var MIGRATED_KEYS = map[string][]string{
+	"Settings":      nil, //replaced by its own contents
+	"FormatVersion": {"ConfigVersion"},
+	"Resources":     {"ResourcesInclude", "ResourcesExclude"},
+	"Codesign":      {"TaskSettings"},
+	"ArtifactTypes": {"TasksExclude"},
+}
+
+// The order of keys in each json object, keyed by the object's path (e.g. "" for the top level, "/TaskSettings" for TaskSettings)
+func jsonKeyOrders(rawJson []byte) (map[string][]string, error) {
+	keyOrders := map[string][]string{}
+	decoder := json.NewDecoder(bytes.NewReader(rawJson))
+	var readValue func(path string) error
+	readValue = func(path string) error {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'):
+			keys := []string{}
+			for decoder.More() {
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				key := fmt.Sprintf("%v", keyToken)
+				keys = append(keys, key)
+				err = readValue(path + "/" + key)
+				if err != nil {
+					return err
+				}
+			}
+			keyOrders[path] = keys
+			_, err = decoder.Token()
+			return err
+		case json.Delim('['):
+			for decoder.More() {
+				err = readValue(path + "/[]")
+				if err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+			return err
+		}
+		return nil
+	}
+	return keyOrders, readValue("")
+}
+
+// The keys of a json object in their original order. Replacements for migrated keys go in the same position, and other new keys go at the end (sorted)
+func orderedKeys(m map[string]interface{}, path string, keyOrders map[string][]string) []string {
+	ret := []string{}
+	added := map[string]bool{}
+	add := func(key string) {
+		if _, keyExists := m[key]; keyExists && !added[key] {
+			ret = append(ret, key)
+			added[key] = true
+		}
+	}
+	for _, key := range keyOrders[path] {
+		add(key)
+		//only when the key has actually been replaced
+		if replacements, isMigrated := MIGRATED_KEYS[key]; isMigrated && path == "" && !added[key] {
+			if key == "Settings" {
+				replacements = keyOrders["/Settings"]
+			}
+			for _, replacement := range replacements {
+				add(replacement)
+			}
+		}
+	}
+	remaining := []string{}
+	for key := range m {
+		if !added[key] {
+			remaining = append(remaining, key)
+		}
+	}
+	sort.Strings(remaining)
+	return append(ret, remaining...)
+}
+
+// Writes json indented with tabs (as per json.MarshalIndent), but keeping the original key order, and without escaping '<', '>' & '&'
+func writeOrderedJson(out *bytes.Buffer, v interface{}, path, indent string, keyOrders map[string][]string) error {
+	switch typedV := v.(type) {
+	case map[string]interface{}:
+		if len(typedV) == 0 {
+			out.WriteString("{}")
+			return nil
+		}
+		out.WriteString("{\n")
+		keys := orderedKeys(typedV, path, keyOrders)
+		for i, key := range keys {
+			out.WriteString(indent + "\t")
+			err := writeJsonScalar(out, key)
+			if err != nil {
+				return err
+			}
+			out.WriteString(": ")
+			err = writeOrderedJson(out, typedV[key], path+"/"+key, indent+"\t", keyOrders)
+			if err != nil {
+				return err
+			}
+			if i < len(keys)-1 {
+				out.WriteString(",")
+			}
+			out.WriteString("\n")
+		}
+		out.WriteString(indent + "}")
+		return nil
+	case []interface{}:
+		if len(typedV) == 0 {
+			out.WriteString("[]")
+			return nil
+		}
+		out.WriteString("[\n")
+		for i, item := range typedV {
+			out.WriteString(indent + "\t")
+			err := writeOrderedJson(out, item, path+"/[]", indent+"\t", keyOrders)
+			if err != nil {
+				return err
+			}
+			if i < len(typedV)-1 {
+				out.WriteString(",")
+			}
+			out.WriteString("\n")
+		}
+		out.WriteString(indent + "]")
+		return nil
+	}
+	return writeJsonScalar(out, v)
+}
+
+func writeJsonScalar(out *bytes.Buffer, v interface{}) error {
+	scalar := &bytes.Buffer{}
+	encoder := json.NewEncoder(scalar)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(v)
+	if err != nil {
+		return err
+	}
+	out.Write(bytes.TrimRight(scalar.Bytes(), "\n"))
+	return nil
+}
+
+// A minimal line diff (longest common subsequence). Lines are prefixed with '-', '+' or ' '
+func lineDiff(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	ret := []string{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ret = append(ret, " "+a[i])
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			ret = append(ret, "+"+b[j])
+			j++
+		default:
+			ret = append(ret, "-"+a[i])
+			i++
+		}
+	}
+	return ret
+}
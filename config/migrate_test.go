@@ -0,0 +1,115 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-migrate")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+	jsonFile := filepath.Join(dir, ".goxc.json")
+	old := `{"FormatVersion": "0.4", "Settings": {"ArtifactTypes": ["zip", "bin"], "Codesign": "Developer ID", "Resources": {"Include": "README*"}, "Tasks": ["xc", "zip"], "PackageVersion": "0.1.0"}}`
+	ioutil.WriteFile(jsonFile, []byte(old), 0644)
+	out := &bytes.Buffer{}
+	err = MigrateConfigFile(jsonFile, out)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	backup, err := ioutil.ReadFile(jsonFile + MIGRATE_BACKUP_EXT)
+	if err != nil || string(backup) != old {
+		t.Errorf("Backup not written: %v", err)
+	}
+	settings, err := LoadJsonConfigs(dir, []string{jsonFile}, false)
+	if err != nil {
+		t.Fatalf("Migrated config doesn't load: %v", err)
+	}
+	if settings.GoxcConfigVersion != GOXC_CONFIG_VERSION || settings.PackageVersion != "0.1.0" || settings.ResourcesInclude != "README*" {
+		t.Errorf("Unexpected settings: %+v", settings)
+	}
+	if strings.Join(settings.Tasks, ",") != "xc,archive-zip" || strings.Join(settings.TasksExclude, ",") != "rmbin" {
+		t.Errorf("Unexpected tasks: %v, excluded %v", settings.Tasks, settings.TasksExclude)
+	}
+	if settings.GetTaskSettingString("codesign", "id") != "Developer ID" {
+		t.Errorf("Unexpected task settings: %+v", settings.TaskSettings)
+	}
+	if !strings.Contains(out.String(), "-"+old) || !strings.Contains(out.String(), "+\t\"ConfigVersion\": \""+GOXC_CONFIG_VERSION+"\",") {
+		t.Errorf("Unexpected diff: %s", out.String())
+	}
+
+	//already migrated
+	out.Reset()
+	err = MigrateConfigFile(jsonFile, out)
+	if err != nil || out.Len() != 0 {
+		t.Errorf("Expected no changes, got %v: %s", err, out.String())
+	}
+}
+
+func TestMigrateConfigFileKeepsFormatting(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-migrate")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+	jsonFile := filepath.Join(dir, ".goxc.json")
+	old := `{"PackageVersion": "0.1.0", "FormatVersion": "0.4", "TaskSettings": {"pkg-build": {"metadata": {"maintainer": "Joe <joe@x.com>"}}}, "BuildConstraints": "linux"}`
+	ioutil.WriteFile(jsonFile, []byte(old), 0644)
+	err = MigrateConfigFile(jsonFile, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	migrated, err := ioutil.ReadFile(jsonFile)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	expected := `{
+	"PackageVersion": "0.1.0",
+	"ConfigVersion": "` + GOXC_CONFIG_VERSION + `",
+	"TaskSettings": {
+		"pkg-build": {
+			"metadata": {
+				"maintainer": "Joe <joe@x.com>"
+			}
+		}
+	},
+	"BuildConstraints": "linux"
+}
+`
+	if string(migrated) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, migrated)
+	}
+}
+
+func TestMigrateConfigFilesYaml(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-migrate")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+	yamlFile := filepath.Join(dir, ".goxc.yaml")
+	ioutil.WriteFile(yamlFile, []byte("# release settings\nFormatVersion: \"0.4\"\nPackageVersion: 0.1.0\n"), 0644)
+	err = MigrateConfigFiles(dir, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	migrated, _ := ioutil.ReadFile(yamlFile)
+	expected := "# release settings\nPackageVersion: 0.1.0\nConfigVersion: \"" + GOXC_CONFIG_VERSION + "\"\n"
+	if string(migrated) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, migrated)
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	diff := lineDiff([]string{"a", "b", "c"}, []string{"a", "x", "c", "d"})
+	expected := []string{" a", "-b", "+x", " c", "+d"}
+	if strings.Join(diff, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected diff: %v", diff)
+	}
+}
@@ -0,0 +1,131 @@
+package config
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"github.com/openxo/goxc/platforms"
+	"github.com/openxo/goxc/typeutils"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// v0.11.x settings which apply to some platforms only.
+// PlatformOverrides are keyed by build constraints, e.g. "linux,arm" or "windows" or "linux darwin".
+type PlatformOverride struct {
+	BuildSettings *BuildSettings                    `json:",omitempty"`
+	Cgo           *CgoSettings                      `json:",omitempty"`
+	Env           []string                          `json:",omitempty"`
+	TaskSettings  map[string]map[string]interface{} `json:",omitempty"`
+}
+
+func platformOverridesFromMap(v interface{}, k string) (map[string]PlatformOverride, error) {
+	m, err := typeutils.ToMap(v, k)
+	if err != nil {
+		return nil, err
+	}
+	overrides := map[string]PlatformOverride{}
+	for constraints, overrideV := range m {
+		overrideM, err := typeutils.ToMap(overrideV, k+":"+constraints)
+		if err != nil {
+			return nil, err
+		}
+		override := PlatformOverride{}
+		for k2, v2 := range overrideM {
+			switch k2 {
+			case "BuildSettings":
+				var bsM map[string]interface{}
+				bsM, err = typeutils.ToMap(v2, k+":"+constraints+":"+k2)
+				if err == nil {
+					override.BuildSettings, err = buildSettingsFromMap(bsM)
+				}
+			case "Cgo":
+				override.Cgo, err = cgoSettingsFromMap(v2, k+":"+constraints+":"+k2)
+			case "Env":
+				override.Env, err = typeutils.ToStringSlice(v2, k+":"+constraints+":"+k2)
+			case "TaskSettings":
+				override.TaskSettings, err = typeutils.ToMapStringMapStringInterface(v2, k+":"+constraints+":"+k2)
+			default:
+				//unrecognised settings are reported by schema validation
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		overrides[constraints] = override
+	}
+	return overrides, nil
+}
+
+// The effective settings for a platform: any matching PlatformOverrides take priority over BuildSettings, Cgo & TaskSettings.
+// Env is appended to.
+// Where several overrides match, the most specific takes priority: the one whose matching clause has the most AND-ed terms
+// (so "linux,arm" beats "linux darwin"), then the one matching the fewest supported platforms (so "linux" beats "!windows"),
+// then the alphabetically-first.
+func (s Settings) ForPlatform(dest platforms.Platform) Settings {
+	matching := mostSpecificFirst{}
+	for constraints := range s.PlatformOverrides {
+		if platforms.MatchesBuildConstraints(constraints, dest) {
+			matching = append(matching, rankOverride(constraints, dest))
+		}
+	}
+	if len(matching) == 0 {
+		return s
+	}
+	sort.Sort(matching)
+	override := PlatformOverride{}
+	for _, ranked := range matching {
+		override = mergeValues(reflect.ValueOf(override), reflect.ValueOf(s.PlatformOverrides[ranked.constraints])).Interface().(PlatformOverride)
+	}
+	ret := Merge(Settings{BuildSettings: override.BuildSettings, Cgo: override.Cgo, TaskSettings: override.TaskSettings}, s)
+	ret.Env = append(append([]string{}, s.Env...), override.Env...)
+	return ret
+}
+
+type rankedOverride struct {
+	constraints string
+	terms       int
+	platforms   int
+}
+
+func rankOverride(constraints string, dest platforms.Platform) rankedOverride {
+	ret := rankedOverride{constraints: constraints}
+	for _, item := range strings.Fields(constraints) {
+		if platforms.MatchesBuildConstraints(item, dest) {
+			terms := len(strings.FieldsFunc(item, func(r rune) bool { return r == ',' }))
+			if terms > ret.terms {
+				ret.terms = terms
+			}
+		}
+	}
+	ret.platforms = len(platforms.ApplyBuildConstraints(constraints, platforms.GetDestPlatforms("", "")))
+	return ret
+}
+
+type mostSpecificFirst []rankedOverride
+
+func (a mostSpecificFirst) Len() int      { return len(a) }
+func (a mostSpecificFirst) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a mostSpecificFirst) Less(i, j int) bool {
+	if a[i].terms != a[j].terms {
+		return a[i].terms > a[j].terms
+	}
+	if a[i].platforms != a[j].platforms {
+		return a[i].platforms < a[j].platforms
+	}
+	return a[i].constraints < a[j].constraints
+}
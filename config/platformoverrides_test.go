@@ -0,0 +1,60 @@
+package config
+
+import (
+	"github.com/openxo/goxc/platforms"
+	"testing"
+)
+
+func TestForPlatform(t *testing.T) {
+	m := map[string]interface{}{
+		"BuildSettings": map[string]interface{}{"LdFlags": "-s", "Tags": "base"},
+		"TaskSettings":  map[string]interface{}{"xc": map[string]interface{}{"GOARM": "6"}},
+		"PlatformOverrides": map[string]interface{}{
+			"linux": map[string]interface{}{
+				"BuildSettings": map[string]interface{}{"Tags": "linux"},
+				"Env":           []interface{}{"CGO_ENABLED=0"}},
+			"linux,arm": map[string]interface{}{
+				"BuildSettings": map[string]interface{}{"Tags": "linux arm"},
+				"TaskSettings":  map[string]interface{}{"xc": map[string]interface{}{"GOARM": "7"}}}}}
+	settings, err := loadSettingsSection(m)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	linuxArm := settings.ForPlatform(platforms.Platform{Os: platforms.LINUX, Arch: platforms.ARM})
+	if *linuxArm.BuildSettings.Tags != "linux arm" || *linuxArm.BuildSettings.LdFlags != "-s" {
+		t.Errorf("Unexpected build settings: %+v", linuxArm.BuildSettings)
+	}
+	if linuxArm.GetTaskSettingString("xc", "GOARM") != "7" || len(linuxArm.Env) != 1 {
+		t.Errorf("Unexpected settings: %+v", linuxArm)
+	}
+	windows := settings.ForPlatform(platforms.Platform{Os: platforms.WINDOWS, Arch: platforms.AMD64})
+	if *windows.BuildSettings.Tags != "base" || windows.GetTaskSettingString("xc", "GOARM") != "6" || len(windows.Env) != 0 {
+		t.Errorf("Overrides applied to the wrong platform: %+v", windows)
+	}
+	if *settings.BuildSettings.Tags != "base" {
+		t.Errorf("Original settings were modified")
+	}
+}
+
+func TestForPlatformPriority(t *testing.T) {
+	m := map[string]interface{}{
+		"PlatformOverrides": map[string]interface{}{
+			"linux darwin": map[string]interface{}{
+				"BuildSettings": map[string]interface{}{"Tags": "or", "LdFlags": "-s"}},
+			"linux,arm": map[string]interface{}{
+				"BuildSettings": map[string]interface{}{"Tags": "and"}},
+			"!windows": map[string]interface{}{
+				"BuildSettings": map[string]interface{}{"Tags": "neg", "LdFlags": "-w", "GcFlags": "-N"}}}}
+	settings, err := loadSettingsSection(m)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	linuxArm := settings.ForPlatform(platforms.Platform{Os: platforms.LINUX, Arch: platforms.ARM})
+	if *linuxArm.BuildSettings.Tags != "and" || *linuxArm.BuildSettings.LdFlags != "-s" || *linuxArm.BuildSettings.GcFlags != "-N" {
+		t.Errorf("Unexpected build settings for linux/arm: %+v", linuxArm.BuildSettings)
+	}
+	darwin := settings.ForPlatform(platforms.Platform{Os: platforms.DARWIN, Arch: platforms.AMD64})
+	if *darwin.BuildSettings.Tags != "or" || *darwin.BuildSettings.LdFlags != "-s" {
+		t.Errorf("Unexpected build settings for darwin: %+v", darwin.BuildSettings)
+	}
+}
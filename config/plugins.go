@@ -0,0 +1,62 @@
+package config
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"github.com/openxo/goxc/typeutils"
+)
+
+// v0.11.x an external task, run as an executable.
+// (Executables named 'goxc-task-<name>' on the PATH are found automatically, and don't need declaring)
+type PluginSettings struct {
+	//path to the executable (or just its name, if it's on the PATH)
+	Command      string
+	Description  string   `json:",omitempty"`
+	Dependencies []string `json:",omitempty"`
+}
+
+func pluginsFromMap(v interface{}, k string) (map[string]PluginSettings, error) {
+	m, err := typeutils.ToMap(v, k)
+	if err != nil {
+		return nil, err
+	}
+	plugins := map[string]PluginSettings{}
+	for name, pluginV := range m {
+		pluginM, err := typeutils.ToMap(pluginV, k+":"+name)
+		if err != nil {
+			return nil, err
+		}
+		plugin := PluginSettings{}
+		for k2, v2 := range pluginM {
+			switch k2 {
+			case "Command":
+				plugin.Command, err = typeutils.ToString(v2, k+":"+name+":"+k2)
+			case "Description":
+				plugin.Description, err = typeutils.ToString(v2, k+":"+name+":"+k2)
+			case "Dependencies":
+				plugin.Dependencies, err = typeutils.ToStringSlice(v2, k+":"+name+":"+k2)
+			default:
+				//0.11.x unrecognised settings are reported by schema validation
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		plugins[name] = plugin
+	}
+	return plugins, nil
+}
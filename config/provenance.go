@@ -0,0 +1,237 @@
+package config
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	ORIGIN_FLAG    = "flag"
+	ORIGIN_DEFAULT = "default"
+)
+
+// Provenance (0.11.x)
+// Each setting's origin is recorded by path (e.g. 'PackageVersion', 'BuildSettings.LdFlags', 'TaskSettings.xc.GOARM').
+// The origin is 'flag', 'default', or the name of the config file which set it.
+// Origins are recorded once: the first source to set a value is the one which takes priority.
+
+// Records the given origin for any set values which don't have an origin yet.
+func (s *Settings) RecordOrigins(origin string) {
+	settingsLeaves(reflect.ValueOf(*s), "", false, func(path string, v interface{}, isSet bool) {
+		if isSet {
+			s.recordOrigin(path, origin)
+		}
+	})
+}
+
+func (s *Settings) recordOrigin(path, origin string) {
+	if s.origins == nil {
+		s.origins = map[string]string{}
+	}
+	if _, keyExists := s.origins[path]; !keyExists {
+		s.origins[path] = origin
+	}
+}
+
+// Where the given setting came from ('flag', 'default' or a config file name). Empty if unknown.
+func (s Settings) Origin(path string) string {
+	return s.origins[path]
+}
+
+// Records the file which set each value, for settings loaded from config files (most important first)
+func (s *Settings) recordFileOrigins(loaded []loadedConfig) {
+	for _, loadedFile := range loaded {
+		mapLeaves(loadedFile.SettingsMap, "", func(path string, v interface{}) {
+			s.recordOrigin(path, loadedFile.File)
+		})
+	}
+}
+
+// Prints each effective setting, with its origin. (For -explain-config)
+// Zero values (e.g. 'false') are included wherever they were set. Secrets are masked.
+func WriteExplanation(w io.Writer, settings Settings) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	var err error
+	settingsLeaves(reflect.ValueOf(settings), "", false, func(path string, v interface{}, isSet bool) {
+		if err != nil {
+			return
+		}
+		if !isSet && settings.Origin(path) == "" {
+			//never set
+			return
+		}
+		value, marshalErr := json.Marshal(v)
+		if marshalErr != nil {
+			value = []byte(fmt.Sprintf("%v", v))
+		}
+		origin := settings.Origin(path)
+		if origin == "" {
+			origin = "(unknown)"
+		}
+		_, err = fmt.Fprintf(tw, "%s\t%s\t%s\n", path, origin, MaskSecrets(string(value)))
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Flush()
+}
+
+// Visits each value in a Settings struct (or part of one), in order. Maps are visited per key (sorted), so their paths match those in config files.
+// 'isSet' is true for non-zero values, and for any value behind a pointer or in a map (e.g. a BuildSettings.Race of false, or TaskSettings.xc.verifyExe of false).
+// A plain zero value might never have been set.
+func settingsLeaves(v reflect.Value, path string, isExplicit bool, visit func(path string, v interface{}, isSet bool)) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			settingsLeaves(v.Elem(), path, true, visit)
+		}
+	case reflect.Interface:
+		if !v.IsNil() {
+			settingsLeaves(v.Elem(), path, isExplicit, visit)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				//unexported
+				continue
+			}
+			name := field.Name
+			tag := strings.Split(field.Tag.Get("json"), ",")[0]
+			if tag != "" && tag != "-" {
+				name = tag
+			}
+			settingsLeaves(v.Field(i), joinSchemaPath(path, name), false, visit)
+		}
+	case reflect.Map:
+		keys := []string{}
+		for _, k := range v.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			settingsLeaves(v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key())), joinSchemaPath(path, k), true, visit)
+		}
+	case reflect.Slice:
+		visit(path, v.Interface(), v.Len() > 0 || (isExplicit && !v.IsNil()))
+	default:
+		if v.IsValid() {
+			visit(path, v.Interface(), isExplicit || !v.IsZero())
+		}
+	}
+}
+
+// Visits each value in a config file's map
+func mapLeaves(v interface{}, path string, visit func(path string, v interface{})) {
+	switch typedV := v.(type) {
+	case nil:
+	case map[string]interface{}:
+		for k, item := range typedV {
+			mapLeaves(item, joinSchemaPath(path, k), visit)
+		}
+	default:
+		visit(path, v)
+	}
+}
+
+// Merges settings together with priority.
+// 0.11.x a generic merge (replacing a hand-written, field-by-field merge):
+// Unset (zero) values are filled from 'low'. Maps are merged per key, recursively.
+// Pointers to structs (e.g. BuildSettings) are merged field by field. Lists are not merged.
+// Neither input is modified.
+func Merge(high Settings, low Settings) Settings {
+	merged := mergeValues(reflect.ValueOf(high), reflect.ValueOf(low)).Interface().(Settings)
+	merged.origins = map[string]string{}
+	for path, origin := range low.origins {
+		merged.origins[path] = origin
+	}
+	for path, origin := range high.origins {
+		merged.origins[path] = origin
+	}
+	return merged
+}
+
+func mergeValues(high, low reflect.Value) reflect.Value {
+	switch high.Kind() {
+	case reflect.Struct:
+		ret := reflect.New(high.Type()).Elem()
+		for i := 0; i < high.NumField(); i++ {
+			if ret.Field(i).CanSet() {
+				ret.Field(i).Set(mergeValues(high.Field(i), low.Field(i)))
+			}
+		}
+		return ret
+	case reflect.Ptr:
+		if high.IsNil() {
+			return low
+		}
+		if low.IsNil() {
+			return high
+		}
+		switch high.Elem().Kind() {
+		case reflect.Struct, reflect.Map:
+			ret := reflect.New(high.Type().Elem())
+			ret.Elem().Set(mergeValues(high.Elem(), low.Elem()))
+			return ret
+		}
+		return high
+	case reflect.Map:
+		if high.Len() == 0 {
+			return low
+		}
+		ret := reflect.MakeMap(high.Type())
+		for _, k := range low.MapKeys() {
+			ret.SetMapIndex(k, low.MapIndex(k))
+		}
+		for _, k := range high.MapKeys() {
+			v := high.MapIndex(k)
+			if lowV := low.MapIndex(k); lowV.IsValid() {
+				v = mergeValues(v, lowV)
+			}
+			ret.SetMapIndex(k, v)
+		}
+		return ret
+	case reflect.Interface:
+		//e.g. nested maps within TaskSettings
+		if high.IsNil() {
+			return low
+		}
+		if !low.IsNil() && high.Elem().Kind() == reflect.Map && high.Elem().Type() == low.Elem().Type() {
+			ret := reflect.New(high.Type()).Elem()
+			ret.Set(mergeValues(high.Elem(), low.Elem()))
+			return ret
+		}
+		return high
+	case reflect.Slice:
+		if high.Len() == 0 {
+			return low
+		}
+		return high
+	}
+	if high.IsZero() {
+		return low
+	}
+	return high
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	ldFlags := "-s"
+	high := Settings{PackageVersion: "1.0", Env: []string{"A=1"},
+		TaskSettings: map[string]map[string]interface{}{"xc": map[string]interface{}{"GOARM": "7"}},
+		BuildSettings: &BuildSettings{LdFlags: &ldFlags}}
+	low := Settings{PackageVersion: "0.1", BuildConstraints: "linux", KeepGoing: true,
+		TaskSettings:  map[string]map[string]interface{}{"xc": map[string]interface{}{"GOARM": "5", "verifyExe": false}},
+		BuildSettings: &BuildSettings{ExtraArgs: []string{"-trimpath"}}}
+	merged := Merge(high, low)
+	if merged.PackageVersion != "1.0" || merged.BuildConstraints != "linux" || !merged.KeepGoing || len(merged.Env) != 1 {
+		t.Errorf("Unexpected merge: %+v", merged)
+	}
+	if merged.GetTaskSettingString("xc", "GOARM") != "7" || merged.GetTaskSetting("xc", "verifyExe") != false {
+		t.Errorf("Task settings not merged: %+v", merged.TaskSettings)
+	}
+	if *merged.BuildSettings.LdFlags != "-s" || len(merged.BuildSettings.ExtraArgs) != 1 {
+		t.Errorf("Build settings not merged: %+v", merged.BuildSettings)
+	}
+	if high.BuildSettings.ExtraArgs != nil || len(high.TaskSettings["xc"]) != 1 {
+		t.Errorf("Input was modified")
+	}
+}
+
+func TestOrigins(t *testing.T) {
+	flags := Settings{PackageVersion: "1.0"}
+	flags.RecordOrigins(ORIGIN_FLAG)
+	configured := Settings{PackageVersion: "0.1", BuildConstraints: "linux"}
+	configured.recordFileOrigins([]loadedConfig{
+		loadedConfig{File: ".goxc.local.json", SettingsMap: map[string]interface{}{"BuildConstraints": "linux"}},
+		loadedConfig{File: ".goxc.json", SettingsMap: map[string]interface{}{"PackageVersion": "0.1", "BuildConstraints": "windows"}}})
+	merged := Merge(flags, configured)
+	merged.ResourcesInclude = "README*"
+	merged.RecordOrigins(ORIGIN_DEFAULT)
+	expected := map[string]string{"PackageVersion": ORIGIN_FLAG, "BuildConstraints": ".goxc.local.json", "ResourcesInclude": ORIGIN_DEFAULT}
+	for path, origin := range expected {
+		if merged.Origin(path) != origin {
+			t.Errorf("%s: expected origin %s, got '%s'", path, origin, merged.Origin(path))
+		}
+	}
+	var buf bytes.Buffer
+	if err := WriteExplanation(&buf, merged); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !strings.Contains(buf.String(), "BuildConstraints  .goxc.local.json  \"linux\"") {
+		t.Errorf("Unexpected explanation:\n%s", buf.String())
+	}
+}
+
+func TestExplainZeroValues(t *testing.T) {
+	race := false
+	configured := Settings{KeepGoing: false, BuildSettings: &BuildSettings{Race: &race},
+		TaskSettings: map[string]map[string]interface{}{"xc": map[string]interface{}{"verifyExe": false}}}
+	configured.recordFileOrigins([]loadedConfig{
+		loadedConfig{File: ".goxc.json", SettingsMap: map[string]interface{}{"KeepGoing": false}}})
+	configured.RecordOrigins(ORIGIN_DEFAULT)
+	var buf bytes.Buffer
+	if err := WriteExplanation(&buf, configured); err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, expected := range []string{"KeepGoing", "BuildSettings.Race", "TaskSettings.xc.verifyExe"} {
+		if !strings.Contains(buf.String(), expected+" ") {
+			t.Errorf("Expected %s in explanation:\n%s", expected, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "PackageVersion") {
+		t.Errorf("Unset values should not be explained:\n%s", buf.String())
+	}
+}
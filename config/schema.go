@@ -0,0 +1,402 @@
+package config
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/openxo/goxc/typeutils"
+	"os/exec"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// json types, as named in messages
+const (
+	SCHEMA_STRING  = "string"
+	SCHEMA_NUMBER  = "number"
+	SCHEMA_BOOLEAN = "boolean"
+	SCHEMA_ARRAY   = "array"
+	SCHEMA_OBJECT  = "object"
+	SCHEMA_ANY     = "any"
+)
+
+// Schema validation (0.11.x)
+// The schema is derived from Settings, BuildSettings & PluginSettings, plus the default settings of each task.
+// Unrecognised keys are warnings (errors in 'strict' mode). Wrong types & missing required fields are errors.
+type schemaNode struct {
+	Type string
+	//known keys, for objects
+	Fields map[string]*schemaNode
+	//schema for values of any other key (nil means other keys are unrecognised)
+	Values *schemaNode
+	//schema for array items
+	Items    *schemaNode
+	Required bool
+}
+
+// A problem found by validating a config file
+type SchemaError struct {
+	File string
+	//e.g. TaskSettings.xc.verifyExe
+	Path string
+	//byte offset in the file (-1 if unknown)
+	Offset    int64
+	Message   string
+	IsWarning bool
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s: '%s' %s", e.File, e.Path, e.Message)
+}
+
+var (
+	schemaLock sync.Mutex
+	strict     bool
+	//task name -> default settings
+	taskDefaults = map[string]map[string]interface{}{}
+)
+
+// Turns schema warnings (e.g. unrecognised settings) into errors
+func SetStrict(isStrict bool) {
+	schemaLock.Lock()
+	defer schemaLock.Unlock()
+	strict = isStrict
+}
+
+func isStrict() bool {
+	schemaLock.Lock()
+	defer schemaLock.Unlock()
+	return strict
+}
+
+// Makes a task's settings known to the schema. (tasks.Register does this)
+func RegisterTaskDefaults(taskName string, defaults map[string]interface{}) {
+	schemaLock.Lock()
+	defer schemaLock.Unlock()
+	taskDefaults[taskName] = defaults
+}
+
+// The schema for a settings section
+func settingsSchema(pluginNames []string) *schemaNode {
+	node := schemaFromType(reflect.TypeOf(Settings{}))
+	//deprecated (but still supported or explicitly rejected)
+	node.Fields["Resources"] = &schemaNode{Type: SCHEMA_OBJECT, Fields: map[string]*schemaNode{
+		"Include": &schemaNode{Type: SCHEMA_STRING},
+		"Exclude": &schemaNode{Type: SCHEMA_STRING}}}
+	node.Fields["ArtifactTypes"] = &schemaNode{Type: SCHEMA_ANY}
+	node.Fields["Codesign"] = &schemaNode{Type: SCHEMA_ANY}
+	//a string or a list
+	node.Fields["Extends"] = &schemaNode{Type: SCHEMA_ANY}
+	node.Fields["TaskSettings"] = taskSettingsSchema(pluginNames)
+	node.Fields["PlatformOverrides"].Values.Fields["TaskSettings"] = taskSettingsSchema(pluginNames)
+	node.Fields["Plugins"].Values.Fields["Command"].Required = true
+	//only settable by a flag (-env)
+	delete(node.Fields, "Env")
+	return node
+}
+
+func taskSettingsSchema(pluginNames []string) *schemaNode {
+	schemaLock.Lock()
+	defer schemaLock.Unlock()
+	node := &schemaNode{Type: SCHEMA_OBJECT, Fields: map[string]*schemaNode{}}
+	for taskName, defaults := range taskDefaults {
+		if defaults == nil {
+			//e.g. plugins. Settings unknown
+			node.Fields[taskName] = &schemaNode{Type: SCHEMA_ANY}
+			continue
+		}
+		taskNode := &schemaNode{Type: SCHEMA_OBJECT, Fields: map[string]*schemaNode{}}
+		for k, v := range defaults {
+			taskNode.Fields[k] = schemaFromValue(v)
+		}
+		node.Fields[taskName] = taskNode
+	}
+	//plugins take any settings
+	for _, pluginName := range pluginNames {
+		if _, keyExists := node.Fields[pluginName]; !keyExists {
+			node.Fields[pluginName] = &schemaNode{Type: SCHEMA_ANY}
+		}
+	}
+	return node
+}
+
+// schema for a go type (as encoded by encoding/json)
+func schemaFromType(t reflect.Type) *schemaNode {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &schemaNode{Type: SCHEMA_STRING}
+	case reflect.Bool:
+		return &schemaNode{Type: SCHEMA_BOOLEAN}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return &schemaNode{Type: SCHEMA_NUMBER}
+	case reflect.Slice, reflect.Array:
+		return &schemaNode{Type: SCHEMA_ARRAY, Items: schemaFromType(t.Elem())}
+	case reflect.Map:
+		return &schemaNode{Type: SCHEMA_OBJECT, Values: schemaFromType(t.Elem())}
+	case reflect.Struct:
+		node := &schemaNode{Type: SCHEMA_OBJECT, Fields: map[string]*schemaNode{}}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				//unexported
+				continue
+			}
+			name := field.Name
+			tag := strings.Split(field.Tag.Get("json"), ",")[0]
+			if tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+			node.Fields[name] = schemaFromType(field.Type)
+		}
+		return node
+	}
+	return &schemaNode{Type: SCHEMA_ANY}
+}
+
+// schema for a default value. Free-form maps (e.g. metadata) take any keys
+func schemaFromValue(v interface{}) *schemaNode {
+	if v == nil {
+		return &schemaNode{Type: SCHEMA_ANY}
+	}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Map:
+		return &schemaNode{Type: SCHEMA_OBJECT, Values: &schemaNode{Type: SCHEMA_ANY}}
+	case reflect.Slice, reflect.Array:
+		return &schemaNode{Type: SCHEMA_ARRAY, Items: &schemaNode{Type: SCHEMA_ANY}}
+	}
+	return schemaFromType(reflect.TypeOf(v))
+}
+
+// json type of a decoded value
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return SCHEMA_STRING
+	case float64:
+		return SCHEMA_NUMBER
+	case bool:
+		return SCHEMA_BOOLEAN
+	case []interface{}:
+		return SCHEMA_ARRAY
+	case map[string]interface{}:
+		return SCHEMA_OBJECT
+	}
+	return "null"
+}
+
+func (n *schemaNode) validate(v interface{}, path string, report func(path, message string, isWarning bool)) {
+	if n.Type == SCHEMA_ANY {
+		return
+	}
+	actual := jsonType(v)
+	if actual != n.Type {
+		report(path, fmt.Sprintf("should be a json %s, not a json %s", n.Type, actual), false)
+		return
+	}
+	switch typedV := v.(type) {
+	case []interface{}:
+		for i, item := range typedV {
+			n.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), report)
+		}
+	case map[string]interface{}:
+		for _, k := range sortedKeys(typedV) {
+			childPath := joinSchemaPath(path, k)
+			if child, keyExists := n.Fields[k]; keyExists {
+				child.validate(typedV[k], childPath, report)
+			} else if n.Values != nil {
+				n.Values.validate(typedV[k], childPath, report)
+			} else {
+				message := "is not a recognised setting"
+				if suggestion := typeutils.ClosestMatch(k, n.fieldNames()); suggestion != "" {
+					message += fmt.Sprintf(". Did you mean '%s'?", suggestion)
+				}
+				report(childPath, message, true)
+			}
+		}
+		for _, k := range n.fieldNames() {
+			if _, keyExists := typedV[k]; !keyExists && n.Fields[k].Required {
+				report(path, fmt.Sprintf("is missing required setting '%s'", k), false)
+			}
+		}
+	}
+}
+
+func (n *schemaNode) fieldNames() []string {
+	names := []string{}
+	for k := range n.Fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinSchemaPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Validates a config file against the schema.
+// Warnings are only returned as errors in 'strict' mode; otherwise they're just logged.
+func validateSchema(rawJson []byte, fileName string, pluginNames []string) []error {
+	var m map[string]interface{}
+	err := json.Unmarshal(rawJson, &m)
+	if err != nil {
+		//reported elsewhere
+		return []error{err}
+	}
+	offsets := keyOffsets(rawJson)
+	isStrictMode := isStrict()
+	errs := []error{}
+	report := func(path, message string, isWarning bool) {
+		offset, keyExists := offsets[path]
+		if !keyExists {
+			offset = -1
+		}
+		err := &SchemaError{File: fileName, Path: path, Offset: offset, Message: message, IsWarning: isWarning && !isStrictMode}
+		printErrorDetails(rawJson, err)
+		if !err.IsWarning {
+			errs = append(errs, err)
+		}
+	}
+	schema := settingsSchema(pluginNames)
+	if s, keyExists := m["Settings"]; keyExists {
+		//pre-0.9 format, with a 'Settings' section
+		fileSchema := &schemaNode{Type: SCHEMA_OBJECT, Fields: map[string]*schemaNode{
+			"Settings":      schema,
+			"ConfigVersion": &schemaNode{Type: SCHEMA_STRING},
+			"FormatVersion": &schemaNode{Type: SCHEMA_STRING}}}
+		fileSchema.validate(map[string]interface{}{"Settings": s}, "", report)
+		for k, v := range m {
+			if k != "Settings" {
+				fileSchema.validate(map[string]interface{}{k: v}, "", report)
+			}
+		}
+	} else {
+		schema.validate(m, "", report)
+	}
+	return errs
+}
+
+// Validates the content of a config file (json, yaml or toml, according to its name) before it's written.
+// Problems are logged as they would be when loading the file.
+func ValidateConfig(fileName string, content []byte) error {
+	rawJson, err := configToJson(content, fileName)
+	if err != nil {
+		return err
+	}
+	m, err := parseJsonFileAsMap(rawJson, fileName)
+	if err != nil {
+		return err
+	}
+	errs := validateSchema(rawJson, fileName, declaredPluginNames(m))
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	_, err = loadSettingsSection(m)
+	return err
+}
+
+// Plugins declared in config, or found on the PATH (so that their TaskSettings are recognised)
+func declaredPluginNames(settingsMap map[string]interface{}) []string {
+	names := []string{}
+	if plugins, ok := settingsMap["Plugins"].(map[string]interface{}); ok {
+		for name := range plugins {
+			names = append(names, name)
+		}
+	}
+	taskSettings, _ := settingsMap["TaskSettings"].(map[string]interface{})
+	schemaLock.Lock()
+	defer schemaLock.Unlock()
+	for name := range taskSettings {
+		if _, isTask := taskDefaults[name]; !isTask {
+			if _, err := exec.LookPath("goxc-task-" + name); err == nil {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// byte offsets of each key in a json document, by path (e.g. 'TaskSettings.xc.verifyExe')
+func keyOffsets(rawJson []byte) map[string]int64 {
+	offsets := map[string]int64{}
+	dec := json.NewDecoder(bytes.NewReader(rawJson))
+	//errors are ignored here. Syntax errors are reported elsewhere
+	walkKeyOffsets(dec, rawJson, "", offsets)
+	return offsets
+}
+
+func walkKeyOffsets(dec *json.Decoder, rawJson []byte, path string, offsets map[string]int64) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return nil
+	}
+	switch delim {
+	case '{':
+		for dec.More() {
+			keyToken, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := keyToken.(string)
+			childPath := joinSchemaPath(path, key)
+			//the offset is just after the key's closing quote. Find the opening quote.
+			end := dec.InputOffset()
+			if end > 0 && int(end) <= len(rawJson) {
+				offsets[childPath] = int64(bytes.LastIndexByte(rawJson[:end-1], '"'))
+			}
+			err = walkKeyOffsets(dec, rawJson, childPath, offsets)
+			if err != nil {
+				return err
+			}
+		}
+	case '[':
+		for i := 0; dec.More(); i++ {
+			err = walkKeyOffsets(dec, rawJson, fmt.Sprintf("%s[%d]", path, i), offsets)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	//closing delimiter
+	_, err = dec.Token()
+	return err
+}
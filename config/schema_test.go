@@ -0,0 +1,47 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSchema(t *testing.T) {
+	RegisterTaskDefaults("test-xc", map[string]interface{}{"verifyExe": true, "GOARM": ""})
+	js := []byte(`{
+	"ConfigVersion": "0.9",
+	"Taskz": ["xc"],
+	"Parallelism": "2",
+	"Plugins": {"lint": {"Description": "no command"}},
+	"TaskSettings": {
+		"test-xc": {"verifyExes": false},
+		"lint": {"anything": 1}
+	}
+}`)
+	errs := validateSchema(js, "test.json", []string{"lint"})
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors (wrong type & missing Command), got %v", errs)
+	}
+	SetStrict(true)
+	defer SetStrict(false)
+	errs = validateSchema(js, "test.json", []string{"lint"})
+	if len(errs) != 4 {
+		t.Fatalf("Expected 4 errors in strict mode, got %v", errs)
+	}
+	found := false
+	for _, err := range errs {
+		schemaErr := err.(*SchemaError)
+		if schemaErr.Path == "TaskSettings.test-xc.verifyExes" {
+			found = true
+			if !strings.Contains(schemaErr.Message, "'verifyExe'") {
+				t.Errorf("Expected a suggestion, got '%s'", schemaErr.Message)
+			}
+			line, _, ok := lineAndColumn(js, schemaErr.Offset)
+			if !ok || line != 7 {
+				t.Errorf("Expected line 7, got %d (%v)", line, ok)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Typo not reported: %v", errs)
+	}
+}
@@ -18,6 +18,7 @@ package config
 */
 
 import (
+	"fmt"
 	"github.com/openxo/goxc/core"
 	"github.com/openxo/goxc/typeutils"
 	"log"
@@ -90,11 +91,44 @@ type Settings struct {
 
 	GoRoot string `json:"-"` //only settable by a flag
 
+	//v0.11.x ignore the build state (i.e. don't skip unchanged work). Only settable by a flag
+	Rebuild bool `json:"-"`
+
 	//TODO?
 	//PreferredGoVersion string `json:",omitempty"` //try to use a go version...
 
 	//v0.10.x
 	Env []string `json:",omitempty"`
+
+	//v0.11.x number of platforms to process concurrently. Defaults to BuildSettings.Processors, or the number of CPUs
+	Parallelism int `json:",omitempty"`
+
+	//v0.11.x user-defined aliases. Each alias is a list of tasks and/or aliases
+	Aliases map[string][]string `json:",omitempty"`
+
+	//v0.11.x only build the Go toolchain's first-class ports (of the platforms selected by Os, Arch & BuildConstraints)
+	FirstClassOnly bool `json:",omitempty"`
+
+	//v0.11.x keep going after a task fails (for other platforms & tasks). Failures are summarised at the end
+	KeepGoing bool `json:",omitempty"`
+
+	//v0.11.x external tasks, keyed by task name
+	Plugins map[string]PluginSettings `json:",omitempty"`
+
+	//v0.11.x parent config files (relative to this file, or to the user config dir). This file's settings take priority.
+	Extends []string `json:",omitempty"`
+
+	//v0.11.x BuildSettings, Env & TaskSettings for some platforms only, keyed by build constraints (e.g. "linux,arm"). See ForPlatform
+	PlatformOverrides map[string]PlatformOverride `json:",omitempty"`
+
+	//v0.11.x the C toolchain for cgo. Normally set per platform, in PlatformOverrides. See CgoSettings
+	Cgo *CgoSettings `json:",omitempty"`
+
+	//v0.11.x name, BuildSettings, platforms & packaging for each binary, keyed by main dir (e.g. "cmd/server"). See BinarySettings
+	Binaries map[string]BinarySettings `json:",omitempty"`
+
+	//v0.11.x where each setting came from, by path (see RecordOrigins)
+	origins map[string]string
 }
 
 func (s Settings) IsVerbose() bool {
@@ -132,7 +166,7 @@ func (s Settings) GetTaskSetting(taskName, settingName string) interface{} {
 	} else {
 		if s.IsVerbose() {
 			log.Printf("No settings for task '%s'", taskName)
-			log.Printf("All task settings: %+v", s.TaskSettings)
+			log.Printf("All task settings: %s", MaskSecrets(fmt.Sprintf("%+v", s.TaskSettings)))
 		}
 	}
 	return nil
@@ -219,115 +253,3 @@ func (settings Settings) GetFullVersionName() string {
 	}
 	return versionName
 }
-
-// DEPRECATED!! Merge settings together with priority.
-// TODO: deprecate in favour of map merge.
-func Merge(high Settings, low Settings) Settings {
-	if high.ArtifactsDest == "" {
-		high.ArtifactsDest = low.ArtifactsDest
-	}
-	//0.6 Adding BuildConstraints
-	if high.BuildConstraints == "" {
-		high.BuildConstraints = low.BuildConstraints
-	}
-
-	if high.ResourcesInclude == "" {
-		high.ResourcesInclude = low.ResourcesInclude
-	}
-	if high.ResourcesExclude == "" {
-		high.ResourcesExclude = low.ResourcesExclude
-	}
-	if high.MainDirsExclude == "" {
-		high.MainDirsExclude = low.MainDirsExclude
-	}
-
-	if high.Arch == "" {
-		high.Arch = low.Arch
-	}
-
-	if high.Os == "" {
-		high.Os = low.Os
-	}
-	if high.BuildConstraints == "" {
-		high.BuildConstraints = low.BuildConstraints
-	}
-	if high.PackageVersion == "" {
-		high.PackageVersion = low.PackageVersion
-	}
-	if high.BranchName == "" {
-		high.BranchName = low.BranchName
-	}
-	if high.PrereleaseInfo == "" {
-		high.PrereleaseInfo = low.PrereleaseInfo
-	}
-	if high.BuildName == "" {
-		high.BuildName = low.BuildName
-	}
-	//TODO: merging of booleans ??
-	if high.Verbosity == "" {
-		high.Verbosity = low.Verbosity
-	}
-	//0.5.0 codesign setting is replaced by task setting 'id'
-	if len(high.Tasks) == 0 {
-		high.Tasks = low.Tasks
-	}
-	//0.6 fixed missing 'merge'
-	if len(high.TasksAppend) == 0 {
-		high.TasksAppend = low.TasksAppend
-	}
-	//0.10 adding 'prepend'
-	if len(high.TasksPrepend) == 0 {
-		high.TasksPrepend = low.TasksPrepend
-	}
-	if len(high.TasksExclude) == 0 {
-		high.TasksExclude = low.TasksExclude
-	}
-	//0.5.0 replaced ArtifactTypes
-	if len(high.TaskSettings) == 0 {
-		high.TaskSettings = low.TaskSettings
-	} else {
-		high.TaskSettings = typeutils.MergeMapsStringMapStringInterface(high.TaskSettings, low.TaskSettings)
-	}
-	//0.9 BuildSettings
-	if high.BuildSettings == nil {
-		high.BuildSettings = low.BuildSettings
-	} else if low.BuildSettings != nil {
-		if high.BuildSettings.Processors == nil {
-			high.BuildSettings.Processors = low.BuildSettings.Processors
-		}
-		if high.BuildSettings.Race == nil {
-			high.BuildSettings.Race = low.BuildSettings.Race
-		}
-		if high.BuildSettings.Verbose == nil {
-			high.BuildSettings.Verbose = low.BuildSettings.Verbose
-		}
-		if high.BuildSettings.PrintCommands == nil {
-			high.BuildSettings.PrintCommands = low.BuildSettings.PrintCommands
-		}
-		if high.BuildSettings.CcFlags == nil {
-			high.BuildSettings.CcFlags = low.BuildSettings.CcFlags
-		}
-		if high.BuildSettings.Compiler == nil {
-			high.BuildSettings.Compiler = low.BuildSettings.Compiler
-		}
-		if high.BuildSettings.GccGoFlags == nil {
-			high.BuildSettings.GccGoFlags = low.BuildSettings.GccGoFlags
-		}
-		if high.BuildSettings.GcFlags == nil {
-			high.BuildSettings.GcFlags = low.BuildSettings.GcFlags
-		}
-		if high.BuildSettings.InstallSuffix == nil {
-			high.BuildSettings.InstallSuffix = low.BuildSettings.InstallSuffix
-		}
-		if high.BuildSettings.LdFlags == nil {
-			high.BuildSettings.LdFlags = low.BuildSettings.LdFlags
-		}
-		if high.BuildSettings.LdFlagsXVars == nil {
-			high.BuildSettings.LdFlagsXVars = low.BuildSettings.LdFlagsXVars
-		}
-		if high.BuildSettings.Tags == nil {
-			high.BuildSettings.Tags = low.BuildSettings.Tags
-		}
-	}
-	return high
-}
@@ -165,7 +165,7 @@ func ParseIncludeResources(basedir, includeResources, excludeResources string, i
 								//return relative filename
 								relativeFilename, err := filepath.Rel(basedir, file)
 								if err != nil {
-									log.Printf("Warning: file %s is not inside %s", file, basedir)
+									Warnf("file %s is not inside %s", file, basedir)
 									allMatches = append(allMatches, file)
 								} else {
 									allMatches = append(allMatches, relativeFilename)
@@ -185,7 +185,11 @@ func ParseIncludeResources(basedir, includeResources, excludeResources string, i
 }
 
 // Get application name (uses dirname)
+// 0.11.x in module mode, it's derived from the import path (so that a clone into a differently-named directory, or a '/v2' module, gets the right name)
 func GetAppName(workingDirectory string) string {
+	if importPath := GetImportPath(workingDirectory); importPath != "" {
+		return ImportPathBase(importPath)
+	}
 	appDirname, err := filepath.Abs(workingDirectory)
 	if err != nil {
 		log.Printf("Error: %v", err)
@@ -249,6 +253,9 @@ func GetOutDestRoot(appName string, artifactsDestSetting string, workingDirector
 	var outDestRoot string
 	if artifactsDestSetting != "" {
 		outDestRoot = artifactsDestSetting
+	} else if moduleRoot := GetModuleRoot(workingDirectory); moduleRoot != "" {
+		//0.11.x in module mode, 'dist' in the module root
+		outDestRoot = filepath.Join(moduleRoot, MODULE_ARTIFACTS_DIRNAME)
 	} else {
 		gobin := os.Getenv("GOBIN")
 		if gobin == "" {
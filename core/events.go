@@ -0,0 +1,145 @@
+package core
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// types of event
+const (
+	EVENT_RUN_START       = "run-start"
+	EVENT_RUN_FINISH      = "run-finish"
+	EVENT_TASK_START      = "task-start"
+	EVENT_TASK_FINISH     = "task-finish"
+	EVENT_PLATFORM_START  = "platform-start"
+	EVENT_PLATFORM_FINISH = "platform-finish"
+	EVENT_ARTIFACT        = "artifact"
+	EVENT_WARNING         = "warning"
+	EVENT_ERROR           = "error"
+
+	EVENT_STATUS_OK      = "ok"
+	EVENT_STATUS_FAILED  = "failed"
+	EVENT_STATUS_SKIPPED = "skipped"
+)
+
+// An event in a goxc run, for CI integration.
+// 0.11.x events are written as newline-delimited JSON, when enabled (see SetEventWriter)
+type Event struct {
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	Task     string    `json:"task,omitempty"`
+	Platform string    `json:"platform,omitempty"`
+	MainDir  string    `json:"mainDir,omitempty"`
+	Status   string    `json:"status,omitempty"`
+	//milliseconds, for 'finish' events
+	Duration int64  `json:"durationMs,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	Sha256   string `json:"sha256,omitempty"`
+	Message  string `json:"message,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+var (
+	eventLock   sync.Mutex
+	eventWriter io.Writer
+	//for output other than events
+	outputWriter io.Writer = os.Stdout
+)
+
+// Enable the event stream, writing events to the given writer. nil disables it (the default).
+func SetEventWriter(w io.Writer) {
+	eventLock.Lock()
+	defer eventLock.Unlock()
+	eventWriter = w
+}
+
+// Where goxc writes output other than events (plans, reports, & the stdout of commands such as 'go test').
+// Set it to stderr when events are written to stdout.
+func SetOutputWriter(w io.Writer) {
+	eventLock.Lock()
+	defer eventLock.Unlock()
+	outputWriter = w
+}
+
+// Where goxc writes output other than events. Stdout by default
+func OutputWriter() io.Writer {
+	eventLock.Lock()
+	defer eventLock.Unlock()
+	return outputWriter
+}
+
+// True if events are being written
+func IsEventStreamEnabled() bool {
+	eventLock.Lock()
+	defer eventLock.Unlock()
+	return eventWriter != nil
+}
+
+// Write an event (if enabled). Safe to call concurrently.
+func EmitEvent(event Event) {
+	eventLock.Lock()
+	defer eventLock.Unlock()
+	if eventWriter == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Could not encode event: %v", err)
+		return
+	}
+	eventWriter.Write(append(data, '\n'))
+}
+
+// milliseconds since start (for event durations)
+func MillisSince(start time.Time) int64 {
+	return int64(time.Since(start) / time.Millisecond)
+}
+
+// Log a warning, and emit it as an event
+func Warnf(format string, v ...interface{}) {
+	message := fmt.Sprintf(format, v...)
+	log.Printf("Warning: %s", message)
+	EmitEvent(Event{Type: EVENT_WARNING, Message: message})
+}
+
+// size and (hex-encoded) sha256 checksum of a file
+func FileSizeAndSha256(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,29 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEmitEvent(t *testing.T) {
+	EmitEvent(Event{Type: EVENT_WARNING, Message: "not enabled"})
+	buf := &bytes.Buffer{}
+	SetEventWriter(buf)
+	defer SetEventWriter(nil)
+	EmitEvent(Event{Type: EVENT_TASK_START, Task: "xc"})
+	Warnf("uh %s", "oh")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 events, got %d: %s", len(lines), buf.String())
+	}
+	var event Event
+	err := json.Unmarshal([]byte(lines[1]), &event)
+	if err != nil {
+		t.Fatalf("Invalid event: %v", err)
+	}
+	if event.Type != EVENT_WARNING || event.Message != "uh oh" || event.Time.IsZero() {
+		t.Errorf("Unexpected event %+v", event)
+	}
+}
@@ -0,0 +1,122 @@
+package core
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Go modules (0.11.x)
+// When the working directory is inside a module (go.mod exists in it or a parent directory), goxc works in module mode:
+// the app name comes from the module path, artifacts default to 'dist' in the module root, and main packages are found with 'go list'.
+// GO111MODULE=off forces GOPATH mode.
+const (
+	GOMOD_FILENAME           = "go.mod"
+	MODULE_ARTIFACTS_DIRNAME = "dist"
+)
+
+// The directory containing go.mod, searching upwards from the working directory. Empty in GOPATH mode.
+func GetModuleRoot(workingDirectory string) string {
+	if os.Getenv("GO111MODULE") == "off" {
+		return ""
+	}
+	dir, err := filepath.Abs(workingDirectory)
+	if err != nil {
+		return ""
+	}
+	for {
+		if fi, err := os.Stat(filepath.Join(dir, GOMOD_FILENAME)); err == nil && !fi.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// The module path declared in go.mod content. Empty if there's no 'module' directive.
+func ParseModulePath(goMod []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(goMod))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i > -1 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "module" {
+			if unquoted, err := strconv.Unquote(fields[1]); err == nil {
+				return unquoted
+			}
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// The module path & root directory for the working directory. Both are empty in GOPATH mode.
+func GetModule(workingDirectory string) (modulePath, moduleRoot string) {
+	moduleRoot = GetModuleRoot(workingDirectory)
+	if moduleRoot == "" {
+		return "", ""
+	}
+	goMod, err := ioutil.ReadFile(filepath.Join(moduleRoot, GOMOD_FILENAME))
+	if err != nil {
+		return "", ""
+	}
+	modulePath = ParseModulePath(goMod)
+	if modulePath == "" {
+		return "", ""
+	}
+	return modulePath, moduleRoot
+}
+
+// The import path of a directory inside a module, e.g. 'github.com/me/app/cmd/tool'. Empty in GOPATH mode.
+func GetImportPath(dir string) string {
+	modulePath, moduleRoot := GetModule(dir)
+	if modulePath == "" {
+		return ""
+	}
+	dirAbs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(moduleRoot, dirAbs)
+	if err != nil || rel == "." {
+		return modulePath
+	}
+	return path.Join(modulePath, filepath.ToSlash(rel))
+}
+
+// The last element of an import path, ignoring any major version suffix. e.g. 'app' for 'github.com/me/app/v2'
+func ImportPathBase(importPath string) string {
+	base := path.Base(importPath)
+	if len(base) > 1 && base[0] == 'v' && importPath != base {
+		if _, err := strconv.Atoi(base[1:]); err == nil {
+			return path.Base(path.Dir(importPath))
+		}
+	}
+	return base
+}
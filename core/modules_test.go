@@ -0,0 +1,66 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseModulePath(t *testing.T) {
+	goMods := map[string]string{
+		"module github.com/me/app\n\ngo 1.21\n":            "github.com/me/app",
+		"// comment\nmodule \"example.com/x\" // quoted\n": "example.com/x",
+		"go 1.21\n": "",
+	}
+	for goMod, expected := range goMods {
+		if actual := ParseModulePath([]byte(goMod)); actual != expected {
+			t.Errorf("unexpected module path '%s' != '%s'", actual, expected)
+		}
+	}
+}
+
+func TestImportPathBase(t *testing.T) {
+	importPaths := map[string]string{
+		"github.com/me/app":        "app",
+		"github.com/me/app/v2":     "app",
+		"github.com/me/app/cmd/v8": "cmd",
+		"github.com/me/vendor":     "vendor",
+		"v2":                       "v2",
+	}
+	for importPath, expected := range importPaths {
+		if actual := ImportPathBase(importPath); actual != expected {
+			t.Errorf("%s: unexpected base '%s' != '%s'", importPath, actual, expected)
+		}
+	}
+}
+
+func TestModule(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "goxc-module")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	moduleRoot := filepath.Join(tmpDir, "app-master")
+	cmdDir := filepath.Join(moduleRoot, "cmd", "tool")
+	err = os.MkdirAll(cmdDir, 0755)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(moduleRoot, GOMOD_FILENAME), []byte("module github.com/me/app/v2\n"), 0644)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if actual := GetImportPath(cmdDir); actual != "github.com/me/app/v2/cmd/tool" {
+		t.Errorf("unexpected import path '%s'", actual)
+	}
+	if actual := GetAppName(moduleRoot); actual != "app" {
+		t.Errorf("unexpected app name '%s'", actual)
+	}
+	if actual := GetOutDestRoot("app", "", cmdDir); actual != filepath.Join(moduleRoot, MODULE_ARTIFACTS_DIRNAME) {
+		t.Errorf("unexpected artifacts dir '%s'", actual)
+	}
+	if actual := GetOutDestRoot("app", filepath.Join(tmpDir, "out"), moduleRoot); actual != filepath.Join(tmpDir, "out") {
+		t.Errorf("unexpected artifacts dir '%s'", actual)
+	}
+}
@@ -0,0 +1,156 @@
+package core
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// kinds of operation
+const (
+	OP_PLAN    = "plan"
+	OP_EXEC    = "exec"
+	OP_MKDIR   = "mkdir"
+	OP_WRITE   = "write"
+	OP_REMOVE  = "remove"
+	OP_ARCHIVE = "archive"
+	OP_HTTP    = "http"
+)
+
+// An operation with side effects - running a command, writing to the filesystem, or making an http request.
+// 0.11.x all tasks go through RunOp (or the helpers below), so that in 'dry-run' mode the operations can be recorded instead of performed.
+type Op struct {
+	Kind        string
+	Description string
+	Details     []string
+}
+
+// Performs (or records) operations
+type OpRunner interface {
+	Run(op Op, f func() error) error
+}
+
+// performs operations
+type realRunner struct{}
+
+func (r realRunner) Run(op Op, f func() error) error {
+	if f == nil {
+		return nil
+	}
+	return f()
+}
+
+// records operations without performing them (for 'dry-run')
+type PlanRunner struct {
+	lock sync.Mutex
+	Ops  []Op
+}
+
+func (r *PlanRunner) Run(op Op, f func() error) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Ops = append(r.Ops, op)
+	return nil
+}
+
+// Writes out the recorded operations
+func (r *PlanRunner) Print(w io.Writer) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	fmt.Fprintf(w, "Dry run - %d operation(s) planned:\n", len(r.Ops))
+	for _, op := range r.Ops {
+		fmt.Fprintf(w, " %-8s %s\n", op.Kind, op.Description)
+		for _, detail := range op.Details {
+			fmt.Fprintf(w, "          %s\n", detail)
+		}
+	}
+}
+
+var opRunner OpRunner = realRunner{}
+
+// Replace the OpRunner. Use a *PlanRunner for a 'dry run'
+func SetOpRunner(runner OpRunner) {
+	opRunner = runner
+}
+
+// True if operations are being recorded rather than performed
+func IsDryRun() bool {
+	_, ok := opRunner.(*PlanRunner)
+	return ok
+}
+
+// Perform an operation (or just record it, in a dry run)
+func RunOp(op Op, f func() error) error {
+	return opRunner.Run(op, f)
+}
+
+// Record an operation which is for information only (e.g. the plan for a dry run)
+func RecordOp(op Op) {
+	opRunner.Run(op, nil)
+}
+
+// os.MkdirAll, via RunOp
+func MkdirAll(path string, perm os.FileMode) error {
+	return RunOp(Op{OP_MKDIR, path, nil}, func() error {
+		return os.MkdirAll(path, perm)
+	})
+}
+
+// ioutil.WriteFile, via RunOp
+func WriteFile(filename string, data []byte, perm os.FileMode) error {
+	return RunOp(Op{OP_WRITE, filename, []string{fmt.Sprintf("%d bytes", len(data))}}, func() error {
+		return ioutil.WriteFile(filename, data, perm)
+	})
+}
+
+// os.Remove, via RunOp
+func Remove(path string) error {
+	return RunOp(Op{OP_REMOVE, path, nil}, func() error {
+		return os.Remove(path)
+	})
+}
+
+// os.RemoveAll, via RunOp
+func RemoveAll(path string) error {
+	return RunOp(Op{OP_REMOVE, path, []string{"(recursive)"}}, func() error {
+		return os.RemoveAll(path)
+	})
+}
+
+type discardCloser struct {
+	io.Writer
+}
+
+func (discardCloser) Close() error {
+	return nil
+}
+
+// Opens a file for writing (truncating or creating it), via RunOp.
+// In a dry run, the content is discarded.
+func CreateFile(filename string, perm os.FileMode) (io.WriteCloser, error) {
+	var file io.WriteCloser = discardCloser{ioutil.Discard}
+	err := RunOp(Op{OP_WRITE, filename, nil}, func() error {
+		var err error
+		file, err = os.OpenFile(filename, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, perm)
+		return err
+	})
+	return file, err
+}
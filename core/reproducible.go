@@ -0,0 +1,86 @@
+package core
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reproducible builds (0.11.x)
+// While a reproducible time is set, archives use it for every entry's modification time (with normalised owners, modes & ordering),
+// and the 'TimeNow' ldflags variable uses it instead of the current time.
+var (
+	reproducibleLock sync.Mutex
+	reproducibleTime *time.Time
+)
+
+// Sets the fixed time for reproducible builds. nil reverts to real timestamps.
+func SetReproducibleTime(t *time.Time) {
+	reproducibleLock.Lock()
+	defer reproducibleLock.Unlock()
+	reproducibleTime = t
+}
+
+// The fixed time, if builds are reproducible
+func GetReproducibleTime() (time.Time, bool) {
+	reproducibleLock.Lock()
+	defer reproducibleLock.Unlock()
+	if reproducibleTime == nil {
+		return time.Time{}, false
+	}
+	return *reproducibleTime, true
+}
+
+// The time to use for reproducible builds: SOURCE_DATE_EPOCH (see https://reproducible-builds.org/specs/source-date-epoch/),
+// or else the time of the last git commit in the working directory.
+func GetSourceDateEpoch(workingDirectory string) (time.Time, error) {
+	if sourceDateEpoch := os.Getenv("SOURCE_DATE_EPOCH"); sourceDateEpoch != "" {
+		secs, err := strconv.ParseInt(sourceDateEpoch, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH '%s'", sourceDateEpoch)
+		}
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	cmd := exec.Command("git", "log", "-1", "--format=%ct")
+	cmd.Dir = workingDirectory
+	out, err := cmd.Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("SOURCE_DATE_EPOCH is not set, and the last commit time is unavailable (%v)", err)
+	}
+	secs, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected commit time '%s'", strings.TrimSpace(string(out)))
+	}
+	return time.Unix(secs, 0).UTC(), nil
+}
+
+// A normalised file mode for archives: 0755 for executables & directories, otherwise 0644
+func ReproducibleMode(mode os.FileMode) os.FileMode {
+	if mode.IsDir() {
+		return os.ModeDir | 0755
+	}
+	if mode&0111 != 0 {
+		return 0755
+	}
+	return 0644
+}
@@ -0,0 +1,20 @@
+package core
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGetSourceDateEpoch(t *testing.T) {
+	orig := os.Getenv("SOURCE_DATE_EPOCH")
+	defer os.Setenv("SOURCE_DATE_EPOCH", orig)
+	os.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	actual, err := GetSourceDateEpoch(".")
+	if err != nil || actual.Unix() != 1700000000 {
+		t.Errorf("unexpected time %v (%v)", actual, err)
+	}
+	os.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	if _, err = GetSourceDateEpoch("."); err == nil {
+		t.Errorf("expected an error for an invalid SOURCE_DATE_EPOCH")
+	}
+}
@@ -27,16 +27,22 @@ import (
 	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
 	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
 	"github.com/openxo/goxc/config"
-	"github.com/openxo/goxc/platforms"
+	"github.com/openxo/goxc/core"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 var (
 	BUILD_COMMANDS = []string{"build", "install"}
+
+	goVersionsLock sync.Mutex
+	//major & minor version, keyed by GOROOT. nil where the version is unknown (e.g. a development build)
+	goVersions = map[string][]int{}
 )
 
 // get list of args to be used in variable interpolation
@@ -59,7 +65,12 @@ func buildInterpolationVars(args map[string]interface{}, fullVersionName string)
 			case "Version":
 				ret[typedV] = fullVersionName
 			case "TimeNow":
-				ret[typedV] = time.Now().Format(time.RFC3339)
+				//0.11.x fixed, for reproducible builds
+				if t, isReproducible := core.GetReproducibleTime(); isReproducible {
+					ret[typedV] = t.Format(time.RFC3339)
+				} else {
+					ret[typedV] = time.Now().Format(time.RFC3339)
+				}
 			}
 
 		default:
@@ -71,18 +82,24 @@ func buildInterpolationVars(args map[string]interface{}, fullVersionName string)
 
 // get list of args to be used in e.g. ldflags variable interpolation
 // v0.9 changed from ldflags-specific to more general flag building
-func buildFlags(args map[string]interface{}, flag string) string {
+// 0.11.x Go 1.5+ requires the 'name=value' form of '-X'. isLegacy gives the old 'name value' form.
+func buildFlags(args map[string]interface{}, flag string, isLegacy bool) string {
 	if len(args) < 1 {
 		return ""
 	}
-	//ret := make([]string, len(args))
+	//0.11.x in a consistent order (so that the command line doesn't change between builds)
+	keys := []string{}
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 	var buf bytes.Buffer
-	for k, v := range args {
-		switch typedV := v.(type) {
-		case string:
-			buf.WriteString(flag + " " + k + " '" + typedV + "' ")
-		default:
-			buf.WriteString(fmt.Sprintf("%s %s '%v' ", flag, k, typedV))
+	for _, k := range keys {
+		v := args[k]
+		if isLegacy {
+			buf.WriteString(fmt.Sprintf("%s %s '%v' ", flag, k, v))
+		} else {
+			buf.WriteString(fmt.Sprintf("%s '%s=%v' ", flag, k, v))
 		}
 	}
 	return buf.String()
@@ -101,15 +118,33 @@ func isBuildCommand(subCmd string) bool {
 // 0.3.1
 // v0.9 changed signature
 func InvokeGo(workingDirectory string, subCmd string, subCmdArgs []string, env []string, settings config.Settings) error {
+	return InvokeGoWithLogger(nil, workingDirectory, subCmd, subCmdArgs, env, settings)
+}
+
+// invoke the go command, logging via the given logger.
+// The command's own output is also written line-by-line to the logger, so that concurrent builds remain readable.
+// A nil logger means the standard logger, with output going straight to stdout/stderr.
+// v0.11.x
+func InvokeGoWithLogger(logger *log.Logger, workingDirectory string, subCmd string, subCmdArgs []string, env []string, settings config.Settings) error {
 	isVerbose := settings.IsVerbose()
 	fullVersionName := settings.GetFullVersionName()
 	//var buildSettings config.BuildSettings
 	buildSettings := settings.BuildSettings
 	goRoot := settings.GoRoot
-	log.Printf("build settings: %s", goRoot)
 	cmdPath := filepath.Join(goRoot, "bin", "go")
 	cmd := exec.Command(cmdPath)
-	RedirectIO(cmd)
+	var stdout, stderr *LogWriter
+	if logger == nil {
+		logger = StdLogger()
+		RedirectIO(cmd)
+	} else {
+		stdout = NewLogWriter(logger)
+		stderr = NewLogWriter(logger)
+		RedirectIOTo(cmd, os.Stdin, stdout, stderr)
+		defer stdout.Flush()
+		defer stderr.Flush()
+	}
+	logger.Printf("build settings: %s", goRoot)
 	args := []string{subCmd}
 	//these commands only apply to `go build` & `go install`
 	if isBuildCommand(subCmd) {
@@ -146,7 +181,7 @@ func InvokeGo(workingDirectory string, subCmd string, subCmdArgs []string, env [
 		}
 		if buildSettings.LdFlagsXVars != nil {
 			//TODO!
-			ldflags = ldflags + " " + buildFlags(buildInterpolationVars(*buildSettings.LdFlagsXVars, fullVersionName), "-X")
+			ldflags = ldflags + " " + buildFlags(buildInterpolationVars(*buildSettings.LdFlagsXVars, fullVersionName), "-X", IsLegacyToolchain(goRoot))
 		}
 		if ldflags != "" {
 			args = append(args, "-ldflags", ldflags)
@@ -154,6 +189,20 @@ func InvokeGo(workingDirectory string, subCmd string, subCmdArgs []string, env [
 		if buildSettings.Tags != nil && *buildSettings.Tags != "" {
 			args = append(args, "-tags", *buildSettings.Tags)
 		}
+		if buildSettings.Mod != nil && *buildSettings.Mod != "" {
+			args = append(args, "-mod="+*buildSettings.Mod)
+		}
+		if buildSettings.Reproducible != nil && *buildSettings.Reproducible {
+			//0.11.x no local paths (Go 1.13+) or VCS state (Go 1.18+) in the binary
+			if IsGoVersionAtLeast(goRoot, 1, 13) {
+				args = append(args, "-trimpath")
+			} else {
+				core.Warnf("-trimpath needs Go 1.13+. The binary may contain local paths")
+			}
+			if IsGoVersionAtLeast(goRoot, 1, 18) {
+				args = append(args, "-buildvcs=false")
+			}
+		}
 		if len(buildSettings.ExtraArgs) > 0 {
 			args = append(args, buildSettings.ExtraArgs...)
 		}
@@ -162,31 +211,47 @@ func InvokeGo(workingDirectory string, subCmd string, subCmdArgs []string, env [
 		}
 	}
 	args = append(args, subCmdArgs...)
-	err := PrepareCmd(cmd, workingDirectory, args, env, isVerbose)
+	err := prepareCmd(logger, cmd, workingDirectory, args, env, isVerbose)
 	if err != nil {
 		return err
 	}
-	log.Printf("invoking '%s %v' from '%s'", cmdPath, PrintableArgs(args), workingDirectory)
-	err = cmd.Start()
+	logger.Printf("invoking '%s %v' from '%s'", cmdPath, PrintableArgs(args), workingDirectory)
+	//0.11.x via RunCmd, so that dry runs just record the command
+	err = RunCmd(cmd, env)
 	if err != nil {
-		log.Printf("Launch error: %s", err)
+		logger.Printf("'go' returned error: %s", err)
 		return err
 	} else {
-		err = cmd.Wait()
-		if err != nil {
-			log.Printf("'go' returned error: %s", err)
-			return err
-		} else {
-			if isVerbose {
-				log.Printf("'go' completed successfully")
-			}
+		if isVerbose && !core.IsDryRun() {
+			logger.Printf("'go' completed successfully")
 		}
 	}
 	return nil
 
 }
 
+// Start a prepared command and wait for it to finish.
+// 0.11.x goes via core.RunOp, so that it's only recorded during a dry run.
+// 'env' is the environment specified by goxc (as opposed to inherited). Only that is recorded (with secrets masked), so that plans are safe to share.
+func RunCmd(cmd *exec.Cmd, env []string) error {
+	details := []string{"dir: " + cmd.Dir}
+	if len(env) > 0 {
+		details = append(details, "env: "+PrintableArgs(config.MaskSecretEnv(env)))
+	}
+	return core.RunOp(core.Op{Kind: core.OP_EXEC, Description: PrintableArgs(cmd.Args), Details: details}, func() error {
+		err := cmd.Start()
+		if err != nil {
+			return err
+		}
+		return cmd.Wait()
+	})
+}
+
 func PrepareCmd(cmd *exec.Cmd, workingDirectory string, args []string, env []string, isVerbose bool) error {
+	return prepareCmd(StdLogger(), cmd, workingDirectory, args, env, isVerbose)
+}
+
+func prepareCmd(logger *log.Logger, cmd *exec.Cmd, workingDirectory string, args []string, env []string, isVerbose bool) error {
 	cmd.Args = append(cmd.Args, args...)
 	cmd.Dir = workingDirectory
 
@@ -203,7 +268,7 @@ func PrepareCmd(cmd *exec.Cmd, workingDirectory string, args []string, env []str
 			specifiedEnvItemSplit := strings.Split(specifiedEnvItem, "=")
 			specifiedEnvKey := specifiedEnvItemSplit[0]
 			if specifiedEnvKey == key {
-				log.Printf("Overriding ENV variable (%s replaces %s)", specifiedEnvItem, thisProcessEnvItem)
+				logger.Printf("Overriding ENV variable (%s replaces %s)", specifiedEnvItem, thisProcessEnvItem)
 				exists = true
 			}
 		}
@@ -212,14 +277,88 @@ func PrepareCmd(cmd *exec.Cmd, workingDirectory string, args []string, env []str
 		}
 	}
 	if isVerbose {
-		log.Printf("(verbose!) all env vars for 'go': %s", cmd.Env)
+		logger.Printf("(verbose!) all env vars for 'go': %s", cmd.Env)
 	}
 	if env != nil && len(env) > 0 {
-		log.Printf("specified env vars for 'go': %s", env)
+		logger.Printf("specified env vars for 'go': %s", env)
 	}
 	return nil
 }
 
+// Returns the output of `go version` for the given GOROOT (e.g. 'go version go1.2 linux/amd64').
+// An empty goroot means the 'go' executable's default GOROOT.
+// 0.11.x
+func GoVersion(goroot string) (string, error) {
+	if goroot == "" {
+		goroot = runtime.GOROOT()
+	}
+	cmd := exec.Command(filepath.Join(goroot, "bin", "go"), "version")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Parses the major & minor version from `go version` output, e.g. 1 & 21 from 'go version go1.21.3 linux/amd64'.
+// Development builds ('go version devel ...') can't be parsed.
+// 0.11.x
+func ParseGoVersion(versionOutput string) (major, minor int, err error) {
+	fields := strings.Fields(versionOutput)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "go1") {
+		return 0, 0, fmt.Errorf("unrecognised go version '%s'", versionOutput)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(fields[2], "go"), ".", 3)
+	major, err = strconv.Atoi(leadingDigits(parts[0]))
+	if err == nil && len(parts) > 1 {
+		minor, err = strconv.Atoi(leadingDigits(parts[1]))
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("unrecognised go version '%s'", versionOutput)
+	}
+	return major, minor, nil
+}
+
+// e.g. '21' from '21rc2'
+func leadingDigits(s string) string {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	return s[:end]
+}
+
+// Whether the Go toolchain in goroot predates Go 1.5.
+// Legacy toolchains need a toolchain built per platform (see the 'toolchain' task), whereas Go 1.5+ cross-compiles with just GOOS & GOARCH.
+// Where the version can't be determined (e.g. a development build), the toolchain is assumed to be modern.
+// 0.11.x
+func IsLegacyToolchain(goroot string) bool {
+	return !IsGoVersionAtLeast(goroot, 1, 5)
+}
+
+// Whether the Go toolchain in goroot is at least the given version (e.g. 1, 18 for flags introduced in Go 1.18).
+// Where the version can't be determined (e.g. a development build), the toolchain is assumed to be modern.
+// 0.11.x
+func IsGoVersionAtLeast(goroot string, major, minor int) bool {
+	goVersionsLock.Lock()
+	defer goVersionsLock.Unlock()
+	version, keyExists := goVersions[goroot]
+	if !keyExists {
+		versionOutput, err := GoVersion(goroot)
+		if err == nil {
+			actualMajor, actualMinor, err := ParseGoVersion(versionOutput)
+			if err == nil {
+				version = []int{actualMajor, actualMinor}
+			}
+		}
+		goVersions[goroot] = version
+	}
+	if version == nil {
+		return true
+	}
+	return version[0] > major || (version[0] == major && version[1] >= minor)
+}
+
 // returns a list of printable args
 func PrintableArgs(args []string) string {
 	ret := ""
@@ -236,7 +375,8 @@ func PrintableArgs(args []string) string {
 	return ret
 }
 func RedirectIO(cmd *exec.Cmd) {
-	RedirectIOTo(cmd, os.Stdin, os.Stdout, os.Stderr)
+	//0.11.x stdout goes to stderr when stdout is used for events
+	RedirectIOTo(cmd, os.Stdin, core.OutputWriter(), os.Stderr)
 }
 
 func RedirectIOTo(cmd *exec.Cmd, myin io.Reader, myout, myerr io.Writer) {
@@ -246,21 +386,3 @@ func RedirectIOTo(cmd *exec.Cmd, myin io.Reader, myout, myerr io.Writer) {
 	cmd.Stdin = myin
 	//return nil, err
 }
-
-// check if cgoEnabled is required.
-//0.2.4 refactored this out
-// TODO not needed for go1.1+. Remove this once go1.0 reaches end of life. (when is that?)
-func CgoEnabled(goos, arch string) string {
-	var cgoEnabled string
-	if goos == runtime.GOOS && arch == runtime.GOARCH {
-		//note: added conditional in line with Dave Cheney, but this combination is not yet supported.
-		if runtime.GOOS == platforms.FREEBSD && runtime.GOARCH == platforms.ARM {
-			cgoEnabled = "0"
-		} else {
-			cgoEnabled = "1"
-		}
-	} else {
-		cgoEnabled = "0"
-	}
-	return cgoEnabled
-}
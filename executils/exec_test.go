@@ -1,8 +1,8 @@
 package executils
 
 import (
-//	"github.com/openxo/goxc/typeutils"
-//	"testing"
+	//	"github.com/openxo/goxc/typeutils"
+	"testing"
 )
 
 /*
@@ -27,3 +27,44 @@ func TestGetInterpolationLdFlags(t *testing.T) {
 	}
 }
 */
+
+func TestParseGoVersion(t *testing.T) {
+	testVersions := map[string][]int{
+		"go version go1.21.3 linux/amd64":  {1, 21},
+		"go version go1.4 darwin/amd64":    {1, 4},
+		"go version go1.22rc2 linux/arm64": {1, 22},
+		"go version go1 linux/386":         {1, 0},
+	}
+	for versionOutput, expected := range testVersions {
+		major, minor, err := ParseGoVersion(versionOutput)
+		if err != nil || major != expected[0] || minor != expected[1] {
+			t.Errorf("%s: unexpected result %d.%d (%v)", versionOutput, major, minor, err)
+		}
+	}
+	if _, _, err := ParseGoVersion("go version devel +abc123 linux/amd64"); err == nil {
+		t.Errorf("Expected an error for a development build")
+	}
+}
+
+func TestBuildFlags(t *testing.T) {
+	args := map[string]interface{}{"main.VERSION": "1.0"}
+	if actual := buildFlags(args, "-X", false); actual != "-X 'main.VERSION=1.0' " {
+		t.Errorf("unexpected result '%s'", actual)
+	}
+	if actual := buildFlags(args, "-X", true); actual != "-X main.VERSION '1.0' " {
+		t.Errorf("unexpected result '%s'", actual)
+	}
+}
+
+func TestIsGoVersionAtLeast(t *testing.T) {
+	goVersionsLock.Lock()
+	goVersions["/goroot-1.12"] = []int{1, 12}
+	goVersions["/goroot-devel"] = nil
+	goVersionsLock.Unlock()
+	if !IsGoVersionAtLeast("/goroot-1.12", 1, 5) || IsGoVersionAtLeast("/goroot-1.12", 1, 13) || IsGoVersionAtLeast("/goroot-1.12", 1, 18) {
+		t.Errorf("Unexpected result for Go 1.12")
+	}
+	if IsLegacyToolchain("/goroot-1.12") || !IsGoVersionAtLeast("/goroot-devel", 1, 18) {
+		t.Errorf("Unknown versions should be treated as modern")
+	}
+}
@@ -0,0 +1,67 @@
+package executils
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"sync"
+)
+
+// a logger equivalent to the standard logger (as configured at the time of calling)
+func StdLogger() *log.Logger {
+	return log.New(os.Stderr, log.Prefix(), log.Flags())
+}
+
+// LogWriter is an io.Writer which writes each line to a logger.
+// Use this to prefix the output of a command (e.g. when several commands are running at once).
+type LogWriter struct {
+	logger *log.Logger
+	buf    bytes.Buffer
+	lock   sync.Mutex
+}
+
+func NewLogWriter(logger *log.Logger) *LogWriter {
+	return &LogWriter{logger: logger}
+}
+
+func (w *LogWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			//incomplete line. Put it back for next time
+			w.buf.WriteString(line)
+			break
+		}
+		w.logger.Print(line)
+	}
+	return len(p), nil
+}
+
+// write any incomplete line
+func (w *LogWriter) Flush() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.buf.Len() > 0 {
+		w.logger.Print(w.buf.String())
+		w.buf.Reset()
+	}
+}
@@ -13,16 +13,24 @@ import (
 //I think plan9 uses a plain old a.out file format
 var (
 	MAGIC_PLAN9_386 = []byte{0, 0, 1, 235}
+	//0.11.x
+	MAGIC_WASM = []byte{0, 'a', 's', 'm'}
 )
 
 func Test(filename, expectedArch, expectedOs string) error {
 	switch expectedOs {
 	case platforms.WINDOWS:
 		return TestPE(filename, expectedArch, expectedOs)
-	case platforms.DARWIN:
+	case platforms.DARWIN, "ios":
 		return TestMachO(filename, expectedArch, expectedOs)
 	case platforms.PLAN9:
 		return TestPlan9Exe(filename, expectedArch, expectedOs)
+	case "js", "wasip1":
+		return TestWasm(filename)
+	case "aix":
+		//0.11.x XCOFF isn't checked
+		log.Printf("File '%s' is not checked (XCOFF)", filename)
+		return nil
 	default:
 		return TestElf(filename, expectedArch, expectedOs)
 	}
@@ -149,3 +157,24 @@ func TestPlan9Exe(filename, expectedArch, expectedOs string) error {
 	return nil
 
 }
+
+// 0.11.x js/wasm & wasip1/wasm
+func TestWasm(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return errors.New("Could not open file")
+	}
+	defer file.Close()
+	b := make([]byte, len(MAGIC_WASM))
+	i, err := file.Read(b)
+	if err != nil || i < len(MAGIC_WASM) {
+		return errors.New("Could not read first 4 bytes of file")
+	}
+	for i := range b {
+		if b[i] != MAGIC_WASM[i] {
+			return errors.New("NOT a WebAssembly module")
+		}
+	}
+	log.Printf("File '%s' is a WebAssembly module", filename)
+	return nil
+}
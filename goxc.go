@@ -22,11 +22,13 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"text/tabwriter"
 	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
 	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
 	"github.com/openxo/goxc/config"
@@ -39,7 +41,7 @@ const (
 	MSG_HELP               = "Usage: goxc [<option(s)>] [<task(s)>]\n"
 	MSG_HELP_TOPICS        = "goxc -h <topic>\n"
 	MSG_HELP_TOPICS_EG     = "More help:\n\tgoxc -h options\nor\n\tgoxc -h tasks\n"
-	MSG_HELP_UNKNOWN_TOPIC = "Unknown topic '%s'. Try 'options' or 'tasks'\n"
+	MSG_HELP_UNKNOWN_TOPIC = "Unknown topic '%s'. Try 'options', 'tasks' or 'platforms'\n"
 )
 
 var (
@@ -67,6 +69,12 @@ var (
 	isWriteConfig        bool
 	isWriteLocalConfig   bool
 	isVerbose            bool
+	isDryRun             bool
+	isJson               bool
+	isStrict             bool
+	isExplainConfig      bool
+	isAcceptDefaults     bool
+	jsonFile             string
 	workingDirectoryFlag string
 	buildConstraints     string
 	env                  config.Strslice
@@ -82,7 +90,35 @@ func printHelp(flagSet *flag.FlagSet) {
 	}
 }
 
+// 0.11.x platforms, with the toolchain's flags
+func printPlatforms() {
+	goroot := goRoot
+	if goroot == "" {
+		goroot = runtime.GOROOT()
+	}
+	err := platforms.LoadPlatforms(goroot, isVerbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not list platforms supported by the Go toolchain in %s: %v\n", goroot, err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Platforms supported by the Go toolchain in %s.\nAll of them are built by default (when no -os, -arch or -bc is given), except ports which need cgo to link (android & ios) unless Cgo is configured for them. Use -first-class to build first-class ports only:\n", goroot)
+	w := tabwriter.NewWriter(os.Stderr, 0, 8, 2, ' ', 0)
+	for _, info := range platforms.ListPlatformInfo() {
+		flags := []string{}
+		if info.FirstClass {
+			flags = append(flags, "first-class")
+		}
+		if info.CgoSupported {
+			flags = append(flags, "cgo")
+		}
+		fmt.Fprintf(w, " %s\t%s\t%s\n", info.GOOS, info.GOARCH, strings.Join(flags, ", "))
+	}
+	w.Flush()
+}
+
 func printHelpTopic(flagSet *flag.FlagSet, topic string) {
+	//0.11.x include plugin tasks
+	tasks.RegisterPlugins(helpSettings())
 	switch topic {
 	case "options":
 		fmt.Fprint(os.Stderr, MSG_HELP)
@@ -101,7 +137,7 @@ func printHelpTopic(flagSet *flag.FlagSet, topic string) {
 			}
 			fmt.Fprintf(os.Stderr, " %s  %s%s\n", task.Name, padding, task.Description)
 		}
-		for alias, taskNames := range tasks.Aliases {
+		for alias, taskNames := range helpAliases() {
 			if len(alias) < 15 {
 				padding = strings.Repeat(" ", 15-len(alias))
 			} else {
@@ -110,11 +146,18 @@ func printHelpTopic(flagSet *flag.FlagSet, topic string) {
 			fmt.Fprintf(os.Stderr, " %s%s alias: %v\n", alias, padding, taskNames)
 		}
 		return
+	case "platforms":
+		//0.11.x from the toolchain
+		printPlatforms()
+		return
 	default:
 		//task help
 		for _, task := range tasks.ListTasks() {
 			if topic == task.Name {
 				fmt.Fprintf(os.Stderr, "Task:\n '%s'\nDescription:\n  %s\n", task.Name, task.Description)
+				if len(task.Dependencies) > 0 {
+					fmt.Fprintf(os.Stderr, "Depends on:\n  %s\n", task.Dependencies)
+				}
 				if task.DefaultSettings != nil {
 					out, err := json.MarshalIndent(map[string]map[string]interface{}{task.Name: task.DefaultSettings}, "", "\t")
 					if err != nil {
@@ -129,9 +172,16 @@ func printHelpTopic(flagSet *flag.FlagSet, topic string) {
 				return
 			}
 		}
-		for alias, taskNames := range tasks.Aliases {
+		aliases := helpAliases()
+		for alias, taskNames := range aliases {
 			if topic == alias {
 				fmt.Fprintf(os.Stderr, "Alias '%s'\n'%s' runs the following tasks:\n  %s\n", alias, alias, taskNames)
+				resolved, err := tasks.ResolveAllAliases(taskNames, config.Settings{Aliases: aliases})
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error resolving alias: %v\n", err)
+				} else if len(resolved) != len(taskNames) {
+					fmt.Fprintf(os.Stderr, "Which resolves to:\n  %s\n", resolved)
+				}
 				return
 			}
 		}
@@ -142,6 +192,25 @@ func printHelpTopic(flagSet *flag.FlagSet, topic string) {
 	fmt.Fprint(os.Stderr, MSG_HELP_TOPICS_EG)
 }
 
+// 0.11.x built-in aliases plus any user-defined aliases in the config.
+func helpAliases() map[string][]string {
+	return tasks.AllAliases(helpSettings())
+}
+
+// 0.11.x config, for help about aliases & plugins.
+// Help is displayed before config is loaded, so config errors are ignored here.
+func helpSettings() config.Settings {
+	name := configName
+	if name == "" {
+		name = core.GOXC_CONFIGNAME_DEFAULT
+	}
+	configuredSettings, err := config.LoadJsonConfigOverrideable(getWorkingDir(), name, true, false, false)
+	if err != nil {
+		return config.Settings{}
+	}
+	return configuredSettings
+}
+
 func printVersion(output *os.File) {
 	fmt.Fprintf(output, " goxc version: %s\n", VERSION)
 	fmt.Fprintf(output, "  build date: %s\n", BUILD_DATE)
@@ -151,26 +220,108 @@ func printVersion(output *os.File) {
 // In theory you could call this with a slice of flags
 func goXC(call []string) {
 	interpretFlags(call)
+	//0.11.x JSON events for CI. Human-readable logging still goes to stderr
+	var output io.Writer = os.Stdout
+	if jsonFile != "" {
+		f, err := os.Create(jsonFile)
+		if err != nil {
+			log.Printf("Could not create JSON event file: %v", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		core.SetEventWriter(f)
+	} else if isJson {
+		//keep stdout for events only. Anything else which writes to stdout (e.g. 'go test') goes to stderr instead
+		core.SetEventWriter(os.Stdout)
+		output = os.Stderr
+	}
+	core.SetOutputWriter(output)
 	workingDirectory := getWorkingDir()
+	//0.11.x create or migrate config before loading it (it might not exist yet, and old config files might not load)
+	if len(settings.Tasks) == 1 && (settings.Tasks[0] == tasks.TASK_INIT || settings.Tasks[0] == tasks.TASK_MIGRATE_CONFIG) {
+		if isDryRun {
+			plan := &core.PlanRunner{}
+			core.SetOpRunner(plan)
+			defer plan.Print(output)
+		}
+		var err error
+		if settings.Tasks[0] == tasks.TASK_INIT {
+			err = tasks.InitConfig(workingDirectory, configName, isAcceptDefaults, os.Stdin, output)
+		} else {
+			err = config.MigrateConfigFiles(workingDirectory, output)
+		}
+		if err != nil {
+			log.Printf("Could not %s config: %v", strings.TrimSuffix(settings.Tasks[0], "-config"), err)
+			os.Exit(1)
+		}
+		return
+	}
 	mergeConfigIntoSettings(workingDirectory)
+	//0.11.x in a dry run, operations are recorded instead of performed, then printed at the end
+	var plan *core.PlanRunner
+	if isDryRun {
+		plan = &core.PlanRunner{}
+		core.SetOpRunner(plan)
+	}
 	if isWriteConfig || isWriteLocalConfig {
 		err := config.WriteJsonConfig(workingDirectory, settings, configName, isWriteLocalConfig)
+		if plan != nil {
+			plan.Print(output)
+		}
 		if err != nil {
 			log.Printf("Could not write config file: %v", err)
+			os.Exit(1)
 		}
 		//0.2.5 writeConfig now just exits after writing config
 	} else {
 		//0.2.3 fillDefaults should only happen after writing config
 		config.FillSettingsDefaults(&settings)
 		tasks.FillTaskSettingsDefaults(&settings)
+		settings.RecordOrigins(config.ORIGIN_DEFAULT)
+		//0.11.x show effective settings & where they came from
+		if isExplainConfig {
+			err := config.WriteExplanation(output, settings)
+			if err != nil {
+				log.Printf("Could not explain config: %v", err)
+				os.Exit(1)
+			}
+			return
+		}
 
 		if settings.IsVerbose() {
-			log.Printf("Final settings %+v", settings)
+			//0.11.x without secrets
+			log.Printf("Final settings %s", config.MaskSecrets(fmt.Sprintf("%+v", settings)))
 		}
 		//2.0.0: Removed PKG_VERSION parsing
+		//0.11.x platforms supported by the toolchain in GoRoot
+		err := platforms.LoadPlatforms(settings.GoRoot, settings.IsVerbose())
+		if err != nil {
+			core.Warnf("Could not list platforms supported by the Go toolchain (using a built-in list instead): %v", err)
+		}
 		destPlatforms := platforms.GetDestPlatforms(settings.Os, settings.Arch)
+		if settings.Os == "" && settings.Arch == "" && settings.BuildConstraints == "" {
+			//0.11.x by default, leave out ports which can't be built without cgo (unless Cgo is configured for them)
+			destPlatforms = platforms.WithoutExternalLinking(destPlatforms, func(p platforms.Platform) bool {
+				cgo := settings.ForPlatform(p).Cgo
+				if cgo == nil {
+					return false
+				}
+				isEnabled, _ := cgo.IsEnabled()
+				return isEnabled
+			})
+		}
 		destPlatforms = platforms.ApplyBuildConstraints(settings.BuildConstraints, destPlatforms)
-		tasks.RunTasks(workingDirectory, destPlatforms, settings)
+		if settings.FirstClassOnly {
+			destPlatforms = platforms.FirstClassPlatforms(destPlatforms)
+		}
+		//0.11.x exit code reflects any failures
+		err = tasks.RunTasks(workingDirectory, destPlatforms, settings)
+		if plan != nil {
+			plan.Print(output)
+		}
+		if err != nil {
+			os.Exit(1)
+		}
 	}
 }
 
@@ -243,6 +394,10 @@ func flagVisitor(f *flag.Flag) {
 		settings.BuildSettings.LdFlagsXVars = fBuildSettings.LdFlagsXVars
 	case "build-tags":
 		settings.BuildSettings.Tags = fBuildSettings.Tags
+	case "build-reproducible":
+		settings.BuildSettings.Reproducible = fBuildSettings.Reproducible
+	case "build-mod":
+		settings.BuildSettings.Mod = fBuildSettings.Mod
 
 	case "env":
 		env, ok := f.Value.(*config.Strslice)
@@ -276,6 +431,8 @@ func interpretFlags(call []string) {
 		if isVerbose {
 			settings.Verbosity = core.VERBOSITY_VERBOSE
 		}
+		//0.11.x unrecognised config settings become errors
+		config.SetStrict(isStrict)
 
 		//0.6 use args. Parse into slice.
 		//settings.Tasks = flagSet.Args()
@@ -390,10 +547,12 @@ func mergeConfiguredSettings(dir string, configName string, isWriteMain, isWrite
 	}
 	configuredSettings, err := config.LoadJsonConfigOverrideable(dir, configName, !isWriteMain && !isWriteLocal, isWriteLocal, settings.IsVerbose())
 	if settings.IsVerbose() {
-		log.Printf("Settings from config %s: %+v : %v", configName, configuredSettings, err)
+		log.Printf("Settings from config %s: %s : %v", configName, config.MaskSecrets(fmt.Sprintf("%+v", configuredSettings)), err)
 	}
 	//TODO: further error handling ?
 	if err == nil {
+		//0.11.x anything set so far came from flags
+		settings.RecordOrigins(config.ORIGIN_FLAG)
 		settings = config.Merge(settings, configuredSettings)
 	}
 	return err
@@ -456,8 +615,8 @@ func setupFlags() *flag.FlagSet {
 	flagSet.StringVar(&configName, "c", "", "config name")
 
 	//TODO deprecate?
-	flagSet.StringVar(&settings.Os, "os", "", "Specify OS (default is all - \"linux darwin windows freebsd openbsd\")")
-	flagSet.StringVar(&settings.Arch, "arch", "", "Specify Arch (default is all - \"386 amd64 arm\")")
+	flagSet.StringVar(&settings.Os, "os", "", "Specify OS (default is all the platforms supported by the Go toolchain. See 'goxc -h platforms')")
+	flagSet.StringVar(&settings.Arch, "arch", "", "Specify Arch (default is all the platforms supported by the Go toolchain. See 'goxc -h platforms')")
 
 	//v0.6
 	flagSet.StringVar(&buildConstraints, "bc", "", "Specify build constraints (e.g. 'linux,arm windows')")
@@ -472,7 +631,7 @@ func setupFlags() *flag.FlagSet {
 	flagSet.StringVar(&settings.BuildName, "bu", "", "Build name (use this for pre-release builds)")
 	//	flagSet.StringVar(&settings.PreferredGoVersion, "goversion", "", "Preferred Go version")
 
-	flagSet.StringVar(&settings.ArtifactsDest, "d", "", "Destination root directory (default=$GOBIN/(appname)-xc)")
+	flagSet.StringVar(&settings.ArtifactsDest, "d", "", "Destination root directory (default=./dist in a Go module, otherwise $GOBIN/(appname)-xc)")
 	flagSet.StringVar(&codesignId, "codesign", "", "identity to sign darwin binaries with (only applied when host OS is 'darwin')")
 
 	flagSet.StringVar(&settings.ResourcesInclude, "resources-include", "", "Include resources in archives (default="+core.RESOURCES_INCLUDE_DEFAULT+")")
@@ -481,6 +640,16 @@ func setupFlags() *flag.FlagSet {
 
 	flagSet.StringVar(&settings.ResourcesExclude, "resources-exclude", "", "Include resources in archives (default="+core.RESOURCES_EXCLUDE_DEFAULT+")")
 	flagSet.StringVar(&settings.MainDirsExclude, "main-dirs-exclude", "", "Exclude given comma-separated directories from 'main' packages")
+	flagSet.BoolVar(&isDryRun, "dry-run", false, "Show what would be done (commands, file writes, archives & uploads), without doing it")
+	flagSet.BoolVar(&isJson, "json", false, "Write events (tasks, platforms, artifacts, warnings & errors) to stdout as newline-delimited JSON")
+	flagSet.StringVar(&jsonFile, "json-file", "", "Write JSON events to the given file instead of stdout")
+	flagSet.BoolVar(&isStrict, "strict", false, "Treat unrecognised settings in config files as errors (instead of warnings)")
+	flagSet.BoolVar(&isExplainConfig, "explain-config", false, "Print each effective setting and where it came from (a flag, a config file, or a default), then exit")
+	flagSet.BoolVar(&isAcceptDefaults, "y", false, "Accept the defaults instead of prompting (for 'goxc init')")
+	flagSet.BoolVar(&settings.Rebuild, "rebuild", false, "Rebuild everything (ignore the build state which lets goxc skip unchanged platforms). The build state covers the working directory, plus go.mod, go.sum & vendor in the module root, but not dependencies in GOPATH or the module cache")
+	flagSet.BoolVar(&settings.FirstClassOnly, "first-class", false, "Only build the Go toolchain's first-class ports (of the platforms selected by -os, -arch & -bc). See 'goxc -h platforms'")
+	flagSet.BoolVar(&settings.KeepGoing, "force", false, "Keep going after a task fails (for other platforms & tasks). Failures are summarised at the end")
+	flagSet.IntVar(&settings.Parallelism, "parallel", 0, "Number of platforms to process at once (default=build-processors, or number of CPUs)")
 
 	//0.2.0 Not easy to 'merge' boolean config items. More flexible to translate them to string options anyway
 	flagSet.BoolVar(&isHelp, "h", false, "Help - options")
@@ -497,7 +666,7 @@ func setupFlags() *flag.FlagSet {
 	flagSet.StringVar(&tasksAppend, "tasks+", "", "Additional tasks to run last. See '-help tasks' for tasks list")
 	flagSet.StringVar(&tasksMinus, "tasks-", "", "Tasks to exclude. See '-help tasks' for tasks list")
 	flagSet.StringVar(&goRoot, "goroot", "", "Specify Go ROOT dir (useful when you have multiple Go installations)")
-	flagSet.BoolVar(&isBuildToolchain, "t", false, "Build cross-compiler toolchain(s). Equivalent to -tasks=toolchain")
+	flagSet.BoolVar(&isBuildToolchain, "t", false, "Build cross-compiler toolchain(s). Only needed before Go 1.5. Equivalent to -tasks=toolchain")
 	flagSet.BoolVar(&isWriteConfig, "wc", false, "(over)write config. Overwrites are additive. Try goxc -wc to produce a starting point.")
 	flagSet.BoolVar(&isWriteLocalConfig, "wlc", false, "write 'local' config")
 
@@ -516,6 +685,8 @@ func setupFlags() *flag.FlagSet {
 	fBuildSettings.InstallSuffix = flagSet.String("build-installsuffix", "", "Build flag")
 	fBuildSettings.LdFlags = flagSet.String("build-ldflags", "", "Build flag")
 	fBuildSettings.Tags = flagSet.String("build-tags", "", "Build flag")
+	fBuildSettings.Reproducible = flagSet.Bool("build-reproducible", false, "Reproducible builds (-trimpath, -buildvcs=false, and timestamps from SOURCE_DATE_EPOCH or the last commit)")
+	fBuildSettings.Mod = flagSet.String("build-mod", "", "Build flag 'mod' (readonly, vendor or mod)")
 
 	env = config.Strslice{}
 	flagSet.Var(&env, "env", "Use env variables")
@@ -528,12 +699,12 @@ func setupFlags() *flag.FlagSet {
 
 func printOptions(flagSet *flag.FlagSet) {
 	fmt.Print("Help Options:\n")
-	taskOptions := []string{"t", "tasks+", "tasks-", "+tasks"}
+	taskOptions := []string{"t", "tasks+", "tasks-", "+tasks", "force", "dry-run", "rebuild", "json", "json-file"}
 	packageVersioningOptions := []string{"pv", "pr", "br", "bu"}
 	deprecatedOptions := []string{"av", "z", "tasks", "h-tasks", "help-tasks", "ht"} //still work but not mentioned
 	platformOptions := []string{"os", "arch", "bc"}
-	cfOptions := []string{"wc", "c"}
-	boolOptions := []string{"h", "v", "version", "t", "wc"}
+	cfOptions := []string{"wc", "c", "strict", "explain-config", "y"}
+	boolOptions := []string{"h", "v", "version", "t", "wc", "force", "dry-run", "rebuild", "json", "strict", "explain-config", "y"}
 
 	//help
 	fmt.Printf("  -h <topic>     Help - default topic is 'options'. Also 'tasks', or any task or alias name.\n")
@@ -544,6 +715,7 @@ func printOptions(flagSet *flag.FlagSet) {
 	fmt.Print("Help Topics:\n")
 	fmt.Printf("  options 	    default)\n")
 	fmt.Printf("  tasks         lists all tasks and aliases\n")
+	fmt.Printf("  platforms     lists the platforms supported by the Go toolchain (see -goroot)\n")
 	fmt.Printf("  <task-name>   task description, task options, and default values\n")
 	fmt.Printf("  <alias-name>  lists an alias's task(s)\n")
 
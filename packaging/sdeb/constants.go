@@ -34,6 +34,43 @@ binary-arch: clean
 	dh_md5sums
 	dh_builddeb
 
+binary: binary-arch`
+	//0.11.x for Go modules: builds from the vendored sources, without network access
+	FILETEMPLATE_DEBIAN_RULES_MODULE = `#!/usr/bin/make -f
+# -*- makefile -*-
+
+# Uncomment this to turn on verbose mode.
+#export DH_VERBOSE=1
+
+export GOFLAGS=-mod=vendor
+export GOPROXY=off
+export GOCACHE=$(CURDIR)/.gocache
+export GOPATH=$(CURDIR)/.gopath
+export GOBIN=$(CURDIR)/bin
+
+PKGDIR=debian/{{.}}
+
+%:
+	dh $@
+
+clean:
+	dh_clean
+	rm -rf $(GOBIN) $(GOCACHE) $(GOPATH)
+
+binary-arch: clean
+	dh_prep
+	dh_installdirs
+	go install ./...
+	mkdir -p $(PKGDIR)/usr/bin
+	cp $(GOBIN)/* $(PKGDIR)/usr/bin/
+	dh_strip
+	dh_compress
+	dh_fixperms
+	dh_installdeb
+	dh_gencontrol
+	dh_md5sums
+	dh_builddeb
+
 binary: binary-arch`
 	FILECONTENT_DEBIAN_COMPAT         = "7"            //TODO: grok significance
 	FILECONTENT_DEBIAN_SOURCE_FORMAT  = "3.0 (native)" //TODO: grok significance
@@ -17,6 +17,7 @@ package sdeb
 */
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/openxo/goxc/archive"
 	"io"
@@ -24,6 +25,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/template"
 )
 
@@ -50,6 +52,68 @@ func SdebGetSourcesAsArchiveItems(codeDir, prefix string) (sources []archive.Arc
 	return sources, err
 }
 
+// All files in a module (except hidden files, temp dirs & the given output dirs), with the vendored dependencies from vendorDir.
+// An empty vendorDir means the module's own 'vendor' dir is used. Archive paths are relative to the module root, under prefix.
+// 0.11.x
+func SdebGetModuleSourcesAsArchiveItems(moduleRoot, vendorDir, prefix string, excludeDirs []string) (sources []archive.ArchiveItem, err error) {
+	sources, err = getFilesAsArchiveItems(moduleRoot, prefix, func(rel string) bool {
+		return vendorDir != "" && rel == "vendor"
+	}, excludeDirs)
+	if err != nil || vendorDir == "" {
+		return sources, err
+	}
+	if _, err := os.Stat(vendorDir); os.IsNotExist(err) {
+		//no dependencies (or a dry run)
+		return sources, nil
+	}
+	vendorSources, err := getFilesAsArchiveItems(vendorDir, filepath.Join(prefix, "vendor"), func(rel string) bool { return false }, nil)
+	return append(sources, vendorSources...), err
+}
+
+func getFilesAsArchiveItems(root, prefix string, isSkipped func(rel string) bool, excludeDirs []string) (items []archive.ArchiveItem, err error) {
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if info.IsDir() {
+			if strings.HasPrefix(info.Name(), ".") || info.Name() == DIRNAME_TEMP || isSkipped(rel) {
+				return filepath.SkipDir
+			}
+			for _, excludeDir := range excludeDirs {
+				if absExcludeDir, err := filepath.Abs(excludeDir); err == nil && absExcludeDir == path {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if strings.HasPrefix(info.Name(), ".") || !info.Mode().IsRegular() {
+			return nil
+		}
+		items = append(items, archive.ArchiveItemFromFileSystem(path, filepath.ToSlash(filepath.Join(prefix, rel))))
+		return nil
+	})
+	return items, err
+}
+
+// The debian/rules file, from a rules template
+// 0.11.x
+func GetRulesContent(rulesTemplate, appName string) ([]byte, error) {
+	tpl, err := template.New("rules").Parse(rulesTemplate)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = tpl.Execute(&buf, appName)
+	return buf.Bytes(), err
+}
+
 func SdebCopySourceRecurse(codeDir, destDir string) (err error) {
 	log.Printf("Globbing %s", codeDir)
 	//get all files and copy into destDir
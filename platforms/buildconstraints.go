@@ -37,6 +37,8 @@ func ApplyBuildConstraints(buildConstraints string, unfilteredPlatforms []Platfo
 		itemNegOs := []string{}
 		itemArch := []string{}
 		itemNegArch := []string{}
+		itemVariant := []string{}
+		itemNegVariant := []string{}
 		for _, part := range parts {
 			isNeg, modulus := isNegative(part)
 			if IsOs(modulus) {
@@ -52,25 +54,72 @@ func ApplyBuildConstraints(buildConstraints string, unfilteredPlatforms []Platfo
 					itemArch = append(itemArch, modulus)
 				}
 
+			} else if IsVariant(modulus) {
+				if isNeg {
+					itemNegVariant = append(itemNegVariant, modulus)
+				} else {
+					itemVariant = append(itemVariant, modulus)
+				}
 			} else {
 				log.Printf("Unrecognised build constraint! Ignoring '%s'", part)
 			}
 		}
-		ret = append(ret, resolveItem(itemOs, itemNegOs, itemArch, itemNegArch, unfilteredPlatforms)...)
+		//0.11.x a variant of none of the named archs would match nothing
+		itemVariant = variantsOfArchs(item, itemArch, itemVariant)
+		itemNegVariant = variantsOfArchs(item, itemArch, itemNegVariant)
+		ret = append(ret, resolveItem(itemOs, itemNegOs, itemArch, itemNegArch, itemVariant, itemNegVariant, unfilteredPlatforms)...)
 	}
 	return ret
 }
 
+// 0.11.x whether a platform satisfies the given build constraints.
+// (ApplyBuildConstraints can add explicitly-named platforms, so it's not a filter on its own)
+func MatchesBuildConstraints(buildConstraints string, p Platform) bool {
+	return ContainsPlatform(ApplyBuildConstraints(buildConstraints, []Platform{p}), p)
+}
+
 // check if a string is a valid architecture name
 func IsArch(part string) bool {
+	for _, info := range getDistPlatforms() {
+		if info.GOARCH == part {
+			return true
+		}
+	}
 	return typeutils.StringSlicePos(ARCHS, part) > -1
 }
 
 // check if a string is a valid OS name
 func IsOs(part string) bool {
+	for _, info := range getDistPlatforms() {
+		if info.GOOS == part {
+			return true
+		}
+	}
 	return typeutils.StringSlicePos(OSES, part) > -1
 }
 
+// the variants which apply to at least one of the archs (all of them if no archs are named). Others are reported & ignored
+func variantsOfArchs(item string, archs, variants []string) []string {
+	if len(archs) == 0 {
+		return variants
+	}
+	ret := []string{}
+	for _, variant := range variants {
+		isVariantOfArch := false
+		for _, arch := range archs {
+			if IsVariantOf(arch, variant) {
+				isVariantOfArch = true
+			}
+		}
+		if isVariantOfArch {
+			ret = append(ret, variant)
+		} else {
+			log.Printf("Unrecognised build constraint! '%s' is not a variant of %s. Ignoring it (in '%s')", variant, strings.Join(archs, " or "), item)
+		}
+	}
+	return ret
+}
+
 func isNegative(part string) (bool, string) {
 	isNeg := strings.HasPrefix(part, "!")
 	if isNeg {
@@ -79,7 +128,7 @@ func isNegative(part string) (bool, string) {
 	return false, part
 }
 
-func resolveItem(itemOses, itemNegOses, itemArchs, itemNegArchs []string, unfilteredPlatforms []Platform) []Platform {
+func resolveItem(itemOses, itemNegOses, itemArchs, itemNegArchs, itemVariants, itemNegVariants []string, unfilteredPlatforms []Platform) []Platform {
 	ret := []Platform{}
 	if len(itemOses) == 0 {
 		//none specified: add all
@@ -102,7 +151,22 @@ func resolveItem(itemOses, itemNegOses, itemArchs, itemNegArchs []string, unfilt
 			itemArchsThisOs = typeutils.StringSliceDelAll(itemArchsThisOs, itemNegArch)
 		}
 		for _, itemArch := range itemArchsThisOs {
-			ret = append(ret, Platform{itemOs, itemArch})
+			variants := getVariants(unfilteredPlatforms, itemOs, itemArch)
+			if len(itemVariants) > 0 {
+				//0.11.x variants were named: only archs with those variants
+				variants = []string{}
+				for _, itemVariant := range itemVariants {
+					if IsVariantOf(itemArch, itemVariant) {
+						variants = append(variants, itemVariant)
+					}
+				}
+			}
+			for _, itemNegVariant := range itemNegVariants {
+				variants = typeutils.StringSliceDelAll(variants, itemNegVariant)
+			}
+			for _, variant := range variants {
+				ret = append(ret, Platform{Os: itemOs, Arch: itemArch, Variant: variant})
+			}
 		}
 	}
 	return ret
@@ -111,12 +175,27 @@ func resolveItem(itemOses, itemNegOses, itemArchs, itemNegArchs []string, unfilt
 func getArchsForOs(sp []Platform, os string) []string {
 	archs := []string{}
 	for _, p := range sp {
-		if p.Os == os {
+		if p.Os == os && !typeutils.StringSliceContains(archs, p.Arch) {
 			archs = append(archs, p.Arch)
 		}
 	}
 	return archs
 }
+
+// the variants of an os/arch in the given platforms. Just "" (no variant) if it has none
+func getVariants(sp []Platform, os, arch string) []string {
+	variants := []string{}
+	for _, p := range sp {
+		if p.Os == os && p.Arch == arch && !typeutils.StringSliceContains(variants, p.Variant) {
+			variants = append(variants, p.Variant)
+		}
+	}
+	if len(variants) == 0 {
+		variants = append(variants, "")
+	}
+	return variants
+}
+
 func getOses(sp []Platform) []string {
 	oses := []string{}
 	for _, p := range sp {
@@ -0,0 +1,200 @@
+package platforms
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+)
+
+// A platform as reported by 'go tool dist list -json' (0.11.x)
+type PlatformInfo struct {
+	GOOS         string
+	GOARCH       string
+	CgoSupported bool
+	FirstClass   bool
+}
+
+func (info PlatformInfo) Platform() Platform {
+	return Platform{Os: info.GOOS, Arch: info.GOARCH}
+}
+
+var (
+	distLock sync.Mutex
+	//nil until loaded. The hard-coded tables are used until then
+	distPlatforms []PlatformInfo
+)
+
+// Loads the platforms supported by the toolchain in goroot, using 'go tool dist list -json'.
+// The result is cached (in the user cache dir), keyed by the go binary, so it's only re-read when the toolchain changes.
+// On failure, the hard-coded tables remain in use.
+func LoadPlatforms(goroot string, verbose bool) error {
+	infos, err := loadDistList(goroot, verbose)
+	if err != nil {
+		return err
+	}
+	SetPlatforms(infos)
+	return nil
+}
+
+// Replaces the supported platforms (nil reverts to the hard-coded tables)
+func SetPlatforms(infos []PlatformInfo) {
+	distLock.Lock()
+	defer distLock.Unlock()
+	distPlatforms = infos
+}
+
+func getDistPlatforms() []PlatformInfo {
+	distLock.Lock()
+	defer distLock.Unlock()
+	return distPlatforms
+}
+
+// The supported platforms, with their cgo-supported & first-class flags.
+// Empty if the toolchain's list hasn't been loaded (the hard-coded tables don't have flags)
+func ListPlatformInfo() []PlatformInfo {
+	return append([]PlatformInfo{}, getDistPlatforms()...)
+}
+
+// Flags for a platform, if the toolchain's list has been loaded
+func GetPlatformInfo(p Platform) (PlatformInfo, bool) {
+	for _, info := range getDistPlatforms() {
+		if info.GOOS == p.Os && info.GOARCH == p.Arch {
+			return info, true
+		}
+	}
+	return PlatformInfo{}, false
+}
+
+// The first-class ports among the given platforms (for FirstClassOnly).
+// With the hard-coded tables, all of them.
+func FirstClassPlatforms(ps []Platform) []Platform {
+	if len(getDistPlatforms()) == 0 {
+		return ps
+	}
+	ret := []Platform{}
+	for _, p := range ps {
+		if info, found := GetPlatformInfo(p); found && info.FirstClass {
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
+
+// Whether a port can only be linked externally, so it can't be built without cgo (as per the go tool's MustLinkExternal)
+func RequiresExternalLinking(p Platform) bool {
+	switch p.Os {
+	case "android":
+		return p.Arch != "arm64"
+	case "ios":
+		return true
+	}
+	return false
+}
+
+// Leaves out ports which can only be linked externally, unless cgo is enabled for them.
+// Used for the default platforms (when no -os, -arch or -bc is given), so that a plain 'goxc' builds without a C toolchain.
+func WithoutExternalLinking(ps []Platform, isCgoEnabled func(Platform) bool) []Platform {
+	ret := []Platform{}
+	for _, p := range ps {
+		if RequiresExternalLinking(p) && !isCgoEnabled(p) {
+			continue
+		}
+		ret = append(ret, p)
+	}
+	return ret
+}
+
+func loadDistList(goroot string, verbose bool) ([]PlatformInfo, error) {
+	goBin := filepath.Join(goroot, "bin", "go")
+	if goroot == "" {
+		goBin = "go"
+	}
+	goBin, err := exec.LookPath(goBin)
+	if err != nil {
+		return nil, err
+	}
+	cacheFile, err := distListCacheFile(goBin)
+	if err == nil {
+		if data, err := ioutil.ReadFile(cacheFile); err == nil {
+			infos, err := parseDistList(data)
+			if err == nil {
+				if verbose {
+					log.Printf("Platforms read from %s", cacheFile)
+				}
+				return infos, nil
+			}
+		}
+	}
+	cmd := exec.Command(goBin, "tool", "dist", "list", "-json")
+	if goroot != "" {
+		cmd.Env = append(os.Environ(), "GOROOT="+goroot)
+	}
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("'go tool dist list -json' failed: %v", err)
+	}
+	infos, err := parseDistList(data)
+	if err != nil {
+		return nil, err
+	}
+	if cacheFile != "" {
+		//a cache failure isn't a problem
+		err = os.MkdirAll(filepath.Dir(cacheFile), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(cacheFile, data, 0644)
+		}
+		if err != nil && verbose {
+			log.Printf("Could not cache platforms: %v", err)
+		}
+	}
+	return infos, nil
+}
+
+func parseDistList(data []byte) ([]PlatformInfo, error) {
+	infos := []PlatformInfo{}
+	err := json.Unmarshal(data, &infos)
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("no platforms listed")
+	}
+	return infos, nil
+}
+
+// e.g. ~/.cache/goxc/dist-list-<hash>.json. The hash covers the go binary's path, size & modification time
+func distListCacheFile(goBin string) (string, error) {
+	fileInfo, err := os.Stat(goBin)
+	if err != nil {
+		return "", err
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%d", goBin, fileInfo.Size(), fileInfo.ModTime().UnixNano())))
+	return filepath.Join(cacheDir, "goxc", "dist-list-"+hex.EncodeToString(sum[:8])+".json"), nil
+}
@@ -0,0 +1,111 @@
+package platforms
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+const testDistList = `[
+	{"GOOS": "android", "GOARCH": "arm64", "CgoSupported": true, "FirstClass": false},
+	{"GOOS": "linux", "GOARCH": "amd64", "CgoSupported": true, "FirstClass": true},
+	{"GOOS": "linux", "GOARCH": "riscv64", "CgoSupported": true, "FirstClass": false},
+	{"GOOS": "windows", "GOARCH": "arm64", "CgoSupported": false, "FirstClass": true}
+]`
+
+func TestDistPlatforms(t *testing.T) {
+	infos, err := parseDistList([]byte(testDistList))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	SetPlatforms(infos)
+	defer SetPlatforms(nil)
+	if !IsOs("android") || !IsArch("riscv64") || !IsOs("darwin") {
+		t.Errorf("Expected android & riscv64 to be recognised, along with the built-in names")
+	}
+	all := GetDestPlatforms("", "")
+	if fmt.Sprintf("%v", all) != "[{android arm64} {linux amd64} {linux riscv64} {windows arm64}]" {
+		t.Errorf("Unexpected platforms: %v", all)
+	}
+	if actual := fmt.Sprintf("%v", ApplyBuildConstraints("linux,!amd64 android", all)); actual != "[{linux riscv64} {android arm64}]" {
+		t.Errorf("Unexpected platforms: %s", actual)
+	}
+	if actual := fmt.Sprintf("%v", FirstClassPlatforms(all)); actual != "[{linux amd64} {windows arm64}]" {
+		t.Errorf("Unexpected first-class platforms: %s", actual)
+	}
+	info, found := GetPlatformInfo(Platform{Os: WINDOWS, Arch: "arm64"})
+	if !found || info.CgoSupported || !info.FirstClass {
+		t.Errorf("Unexpected info: %+v", info)
+	}
+	//hard-coded fallback
+	SetPlatforms(nil)
+	if len(FirstClassPlatforms(GetDestPlatforms("", ""))) != len(SUPPORTED_PLATFORMS_1_1) {
+		t.Errorf("Expected all the built-in platforms")
+	}
+}
+
+func TestLoadPlatforms(t *testing.T) {
+	cacheDir, err := ioutil.TempDir("", "goxc-cache")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(cacheDir)
+	for _, env := range []string{"XDG_CACHE_HOME", "HOME", "LocalAppData"} {
+		defer os.Setenv(env, os.Getenv(env))
+		os.Setenv(env, cacheDir)
+	}
+	defer SetPlatforms(nil)
+	for i := 0; i < 2; i++ {
+		//the second time is from the cache
+		err = LoadPlatforms(runtime.GOROOT(), false)
+		if err != nil {
+			t.Skipf("go tool dist list unavailable: %v", err)
+		}
+		info, found := GetPlatformInfo(Platform{Os: runtime.GOOS, Arch: runtime.GOARCH})
+		if !found {
+			t.Errorf("Expected the host platform to be listed")
+		}
+		if runtime.GOOS == LINUX && runtime.GOARCH == AMD64 && !info.FirstClass {
+			t.Errorf("Expected linux/amd64 to be first-class")
+		}
+	}
+	matches, _ := filepath.Glob(filepath.Join(cacheDir, "*", "goxc", "dist-list-*.json"))
+	matches2, _ := filepath.Glob(filepath.Join(cacheDir, "goxc", "dist-list-*.json"))
+	if len(matches)+len(matches2) != 1 {
+		t.Errorf("Expected a cache file in %s", cacheDir)
+	}
+}
+
+func TestDefaultPlatformsBuildWithoutCgo(t *testing.T) {
+	defer SetPlatforms(nil)
+	err := LoadPlatforms(runtime.GOROOT(), false)
+	if err != nil {
+		t.Skipf("go tool dist list unavailable: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "goxc-platforms")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module hello\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	noCgo := func(Platform) bool { return false }
+	defaults := WithoutExternalLinking(GetDestPlatforms("", ""), noCgo)
+	for _, p := range defaults {
+		//-n checks the build (including the link mode) without running it
+		cmd := exec.Command(filepath.Join(runtime.GOROOT(), "bin", "go"), "build", "-n", "-o", os.DevNull, ".")
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "CGO_ENABLED=0", "GOOS="+p.Os, "GOARCH="+p.Arch, "GOFLAGS=", "GO111MODULE=on")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("%s/%s: %v\n%s", p.Os, p.Arch, err, out)
+		}
+	}
+	//unless cgo is enabled for them
+	if len(WithoutExternalLinking(GetDestPlatforms("", ""), func(Platform) bool { return true })) != len(GetDestPlatforms("", "")) {
+		t.Errorf("Expected all platforms when cgo is enabled")
+	}
+}
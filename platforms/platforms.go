@@ -44,38 +44,49 @@ const (
 type Platform struct {
 	Os   string
 	Arch string
+	//0.11.x e.g. 'v7' for GOARM=7. See VARIANTS
+	Variant string
 }
 
+// 0.11.x these tables are only a fallback, for when 'go tool dist list -json' isn't available. See LoadPlatforms
 var (
 	OSES                    = []string{DARWIN, LINUX, FREEBSD, NETBSD, OPENBSD, PLAN9, WINDOWS}
 	ARCHS                   = []string{X86, AMD64, ARM}
 	SUPPORTED_PLATFORMS_1_0 = []Platform{
-		Platform{DARWIN, X86},
-		Platform{DARWIN, AMD64},
-		Platform{LINUX, X86},
-		Platform{LINUX, AMD64},
-		Platform{LINUX, ARM},
-		Platform{FREEBSD, X86},
-		Platform{FREEBSD, AMD64},
-		// Platform{FREEBSD, ARM},
+		Platform{Os: DARWIN, Arch: X86},
+		Platform{Os: DARWIN, Arch: AMD64},
+		Platform{Os: LINUX, Arch: X86},
+		Platform{Os: LINUX, Arch: AMD64},
+		Platform{Os: LINUX, Arch: ARM},
+		Platform{Os: FREEBSD, Arch: X86},
+		Platform{Os: FREEBSD, Arch: AMD64},
+		// Platform{Os: FREEBSD, Arch: ARM},
 		// couldnt build toolchain for netbsd using a linux 386 host: 2013-02-19
-		//	Platform{NETBSD, X86},
-		//	Platform{NETBSD, AMD64},
-		Platform{OPENBSD, X86},
-		Platform{OPENBSD, AMD64},
-		Platform{WINDOWS, X86},
-		Platform{WINDOWS, AMD64}}
+		//	Platform{Os: NETBSD, Arch: X86},
+		//	Platform{Os: NETBSD, Arch: AMD64},
+		Platform{Os: OPENBSD, Arch: X86},
+		Platform{Os: OPENBSD, Arch: AMD64},
+		Platform{Os: WINDOWS, Arch: X86},
+		Platform{Os: WINDOWS, Arch: AMD64}}
 	NEW_PLATFORMS_1_1 = []Platform{
-		Platform{FREEBSD, ARM},
-		Platform{NETBSD, X86},
-		Platform{NETBSD, AMD64},
-		Platform{NETBSD, ARM},
-		Platform{PLAN9, X86}}
+		Platform{Os: FREEBSD, Arch: ARM},
+		Platform{Os: NETBSD, Arch: X86},
+		Platform{Os: NETBSD, Arch: AMD64},
+		Platform{Os: NETBSD, Arch: ARM},
+		Platform{Os: PLAN9, Arch: X86}}
 
 	SUPPORTED_PLATFORMS_1_1 = append(append([]Platform{}, SUPPORTED_PLATFORMS_1_0...), NEW_PLATFORMS_1_1...)
 )
 
 func getSupportedPlatforms() []Platform {
+	//0.11.x from the toolchain, if loaded
+	if infos := getDistPlatforms(); len(infos) > 0 {
+		ret := []Platform{}
+		for _, info := range infos {
+			ret = append(ret, info.Platform())
+		}
+		return ret
+	}
 	if strings.HasPrefix(runtime.Version(), "go1.0") {
 		return SUPPORTED_PLATFORMS_1_0
 	}
@@ -84,7 +95,7 @@ func getSupportedPlatforms() []Platform {
 
 func ContainsPlatform(haystack []Platform, needle Platform) bool {
 	for _, p := range haystack {
-		if p.Os == needle.Os && p.Arch == needle.Arch {
+		if p.Os == needle.Os && p.Arch == needle.Arch && p.Variant == needle.Variant {
 			return true
 		}
 	}
@@ -0,0 +1,101 @@
+package platforms
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"strings"
+)
+
+// Architecture variants (0.11.x)
+// A variant selects a sub-architecture with GOARM, GOAMD64, GO386 or GOMIPS (e.g. linux,arm,v7 or linux,amd64,v3).
+// ARM variants are named 'v5', 'v6' & 'v7' (i.e. GOARM=5,6,7). Others are named as per their environment variable's values.
+var VARIANTS = map[string][]string{
+	ARM:        []string{"v5", "v6", "v7"},
+	AMD64:      []string{"v1", "v2", "v3", "v4"},
+	X86:        []string{"sse2", "softfloat"},
+	"mips":     []string{"hardfloat", "softfloat"},
+	"mipsle":   []string{"hardfloat", "softfloat"},
+	"mips64":   []string{"hardfloat", "softfloat"},
+	"mips64le": []string{"hardfloat", "softfloat"},
+}
+
+// the environment variable which selects each architecture's variant
+var VARIANT_ENV_VARS = map[string]string{
+	ARM:        "GOARM",
+	AMD64:      "GOAMD64",
+	X86:        "GO386",
+	"mips":     "GOMIPS",
+	"mipsle":   "GOMIPS",
+	"mips64":   "GOMIPS64",
+	"mips64le": "GOMIPS64",
+}
+
+// check if a string is a valid variant name (for any architecture)
+func IsVariant(part string) bool {
+	for _, variants := range VARIANTS {
+		for _, variant := range variants {
+			if variant == part {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// check if a variant applies to the given architecture
+func IsVariantOf(arch, variant string) bool {
+	for _, v := range VARIANTS[arch] {
+		if v == variant {
+			return true
+		}
+	}
+	return false
+}
+
+// The architecture, with any variant. e.g. 'arm_v7', or just 'arm'. Used in file & directory names
+func (p Platform) ArchName() string {
+	if p.Variant == "" {
+		return p.Arch
+	}
+	return p.Arch + "_" + p.Variant
+}
+
+// e.g. '{linux arm v7}', or '{linux amd64}' without a variant (as printed before variants were added)
+func (p Platform) String() string {
+	if p.Variant == "" {
+		return "{" + p.Os + " " + p.Arch + "}"
+	}
+	return "{" + p.Os + " " + p.Arch + " " + p.Variant + "}"
+}
+
+// e.g. 'linux_arm_v7', or 'linux_amd64' without a variant
+func (p Platform) Name() string {
+	return p.Os + "_" + p.ArchName()
+}
+
+// The environment variable which selects the variant, e.g. GOARM=7. Empty without a variant
+func (p Platform) VariantEnv() []string {
+	envVar, keyExists := VARIANT_ENV_VARS[p.Arch]
+	if p.Variant == "" || !keyExists {
+		return []string{}
+	}
+	value := p.Variant
+	if p.Arch == ARM {
+		value = strings.TrimPrefix(value, "v")
+	}
+	return []string{envVar + "=" + value}
+}
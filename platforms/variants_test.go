@@ -0,0 +1,50 @@
+package platforms
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestVariantBuildConstraints(t *testing.T) {
+	testBCs := map[string]string{
+		"linux,arm,v6 linux,arm,v7 linux,amd64,v3": "[{linux arm v6} {linux arm v7} {linux amd64 v3}]",
+		"linux,v7":            "[{linux arm v7}]",
+		"linux,arm,v5,v6,!v5": "[{linux arm v6}]",
+		"linux,!arm":          "[{linux 386} {linux amd64}]",
+		//not a variant of amd64: ignored
+		"linux,amd64,v7": "[{linux amd64}]",
+	}
+	for buildConstraints, expectedPlatforms := range testBCs {
+		targets := ApplyBuildConstraints(buildConstraints, SUPPORTED_PLATFORMS_1_0)
+		if actual := fmt.Sprintf("%v", targets); actual != expectedPlatforms {
+			t.Errorf("%s: unexpected result %v != %v", buildConstraints, expectedPlatforms, actual)
+		}
+	}
+	//variants are kept when filtering
+	withVariants := []Platform{{Os: LINUX, Arch: ARM, Variant: "v6"}, {Os: LINUX, Arch: ARM, Variant: "v7"}, {Os: LINUX, Arch: AMD64}}
+	if actual := fmt.Sprintf("%v", ApplyBuildConstraints("linux,arm", withVariants)); actual != "[{linux arm v6} {linux arm v7}]" {
+		t.Errorf("Unexpected platforms: %s", actual)
+	}
+	if !MatchesBuildConstraints("linux,arm,v7", withVariants[1]) || MatchesBuildConstraints("linux,arm,v7", withVariants[0]) {
+		t.Errorf("Unexpected variant match")
+	}
+}
+
+func TestVariantNames(t *testing.T) {
+	p := Platform{Os: LINUX, Arch: ARM, Variant: "v7"}
+	if p.Name() != "linux_arm_v7" || p.ArchName() != "arm_v7" {
+		t.Errorf("Unexpected names: %s, %s", p.Name(), p.ArchName())
+	}
+	if !reflect.DeepEqual(p.VariantEnv(), []string{"GOARM=7"}) {
+		t.Errorf("Unexpected env: %v", p.VariantEnv())
+	}
+	p = Platform{Os: LINUX, Arch: AMD64, Variant: "v3"}
+	if !reflect.DeepEqual(p.VariantEnv(), []string{"GOAMD64=v3"}) {
+		t.Errorf("Unexpected env: %v", p.VariantEnv())
+	}
+	p = Platform{Os: LINUX, Arch: AMD64}
+	if p.Name() != "linux_amd64" || len(p.VariantEnv()) != 0 {
+		t.Errorf("Unexpected name/env: %s, %v", p.Name(), p.VariantEnv())
+	}
+}
@@ -0,0 +1,81 @@
+package source
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// A 'main' package in a module (0.11.x)
+type MainPackage struct {
+	ImportPath string
+	Dir        string
+}
+
+// Finds the main packages under root (inside a module) using 'go list', so that build constraints, vendor dirs, testdata dirs & nested modules are treated as the go tool treats them.
+// Exclusion globs match either directories (relative to root, as in FindMainDirs) or import paths.
+func FindMainPackages(root, goBin string, excludingGlobs []string) ([]MainPackage, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(goBin, "list", "-e", "-f", "{{if eq .Name \"main\"}}{{.ImportPath}}\t{{.Dir}}{{end}}", "./...")
+	cmd.Dir = root
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("'go list' failed: %v", err)
+	}
+	return parseMainPackages(string(out), root, excludingGlobs), nil
+}
+
+// parses 'go list' output (one 'importPath<tab>dir' line per main package)
+func parseMainPackages(output, root string, excludingGlobs []string) []MainPackage {
+	mainPackages := []MainPackage{}
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "\t", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		mainPackage := MainPackage{ImportPath: parts[0], Dir: parts[1]}
+		if isExcluded(root, mainPackage.Dir, excludingGlobs) || isImportPathExcluded(mainPackage.ImportPath, excludingGlobs) {
+			continue
+		}
+		mainPackages = append(mainPackages, mainPackage)
+	}
+	return mainPackages
+}
+
+// whether an import path matches any of the globs. A glob also excludes everything beneath it
+func isImportPathExcluded(importPath string, excludingGlobs []string) bool {
+	for _, exclGlob := range excludingGlobs {
+		exclGlob = filepath.ToSlash(exclGlob)
+		if matches, err := path.Match(exclGlob, importPath); err == nil && matches {
+			log.Printf("Main package '%s' excluded by glob '%s'", importPath, exclGlob)
+			return true
+		}
+		if strings.HasPrefix(importPath, exclGlob+"/") {
+			log.Printf("Main package '%s' excluded because it is in '%s'", importPath, exclGlob)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+package source
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMainPackages(t *testing.T) {
+	output := "example.com/app\t/src/app\n\nexample.com/app/cmd/tool\t/src/app/cmd/tool\nexample.com/app/examples/demo\t/src/app/examples/demo\n"
+	actual := parseMainPackages(output, "/src/app", []string{"example.com/app/examples"})
+	expected := []MainPackage{{ImportPath: "example.com/app", Dir: "/src/app"}, {ImportPath: "example.com/app/cmd/tool", Dir: "/src/app/cmd/tool"}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected main packages %v", actual)
+	}
+	actual = parseMainPackages(output, "/src/app", []string{"cmd"})
+	if len(actual) != 2 || actual[1].ImportPath != "example.com/app/examples/demo" {
+		t.Errorf("unexpected main packages %v", actual)
+	}
+}
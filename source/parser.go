@@ -77,30 +77,7 @@ func FindMainDirs(root string, excludingGlobs []string) ([]string, error) {
 			if err != nil {
 				log.Printf("Abs error: %s: %v", filepath.Dir(name), err)
 			} else {
-				excluded := false
-				for _, exclGlob := range excludingGlobs {
-					//log.Printf("Glob testing: %s matches %s", filepath.Join(root, exclGlob), mainDir)
-					matches, err := filepath.Match(filepath.Join(root, exclGlob), mainDir)
-					if err != nil {
-						//ignore this exclusion glob
-						log.Printf("Glob error: %s: %s", exclGlob, err)
-					} else if matches {
-						log.Printf("Main dir '%s' excluded by glob '%s'", mainDir, exclGlob)
-						excluded = true
-					} else {
-						absExcl, err := filepath.Abs(filepath.Join(root, exclGlob))
-						if err != nil {
-							//ignore
-							log.Printf("Abs error: %s: %v", filepath.Join(root, exclGlob), err)
-						} else if strings.HasPrefix(mainDir, absExcl) {
-							log.Printf("Main dir '%s' excluded because it is in '%s'", mainDir, absExcl)
-							excluded = true
-						} else {
-							//log.Printf("Main dir '%s' is NOT in '%s'", mainDir, absExcl)
-						}
-					}
-
-				}
+				excluded := isExcluded(root, mainDir, excludingGlobs)
 				if !excluded {
 					alreadyThere := false
 					for _, v := range mainDirs {
@@ -189,3 +166,31 @@ func FindValue(f *ast.File, name string, toks []token.Token) *ast.BasicLit {
 	//return value, found
 	return ret
 }
+
+// whether a main dir is excluded by any of the globs (relative to root). A glob also excludes everything beneath it
+func isExcluded(root, mainDir string, excludingGlobs []string) bool {
+	excluded := false
+	for _, exclGlob := range excludingGlobs {
+		//log.Printf("Glob testing: %s matches %s", filepath.Join(root, exclGlob), mainDir)
+		matches, err := filepath.Match(filepath.Join(root, exclGlob), mainDir)
+		if err != nil {
+			//ignore this exclusion glob
+			log.Printf("Glob error: %s: %s", exclGlob, err)
+		} else if matches {
+			log.Printf("Main dir '%s' excluded by glob '%s'", mainDir, exclGlob)
+			excluded = true
+		} else {
+			absExcl, err := filepath.Abs(filepath.Join(root, exclGlob))
+			if err != nil {
+				//ignore
+				log.Printf("Abs error: %s: %v", filepath.Join(root, exclGlob), err)
+			} else if strings.HasPrefix(mainDir, absExcl) {
+				log.Printf("Main dir '%s' excluded because it is in '%s'", mainDir, absExcl)
+				excluded = true
+			} else {
+				//log.Printf("Main dir '%s' is NOT in '%s'", mainDir, absExcl)
+			}
+		}
+	}
+	return excluded
+}
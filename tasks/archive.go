@@ -25,7 +25,6 @@ import (
 	"github.com/openxo/goxc/core"
 	"github.com/openxo/goxc/platforms"
 	"log"
-	"os"
 	"path/filepath"
 )
 
@@ -35,12 +34,14 @@ func init() {
 		"archive-zip",
 		"Create a zip archive. By default, 'zip' format is used for all platforms except Linux",
 		runTaskArchiveZip,
-		map[string]interface{}{"platforms": "!linux", "include-top-level-dir": "!windows"}})
+		map[string]interface{}{"platforms": "!linux", "include-top-level-dir": "!windows"},
+		[]string{TASK_XC, TASK_COPY_RESOURCES}})
 	Register(Task{
 		"archive-tar-gz",
 		"Create a compressed archive. Linux-only by default",
 		runTaskArchiveTarGz,
-		map[string]interface{}{"platforms": "linux", "include-top-level-dir": "!windows"}})
+		map[string]interface{}{"platforms": "linux", "include-top-level-dir": "!windows"},
+		[]string{TASK_XC, TASK_COPY_RESOURCES}})
 
 }
 
@@ -75,38 +76,47 @@ func runTaskArchive(tp TaskParams, taskName string) error {
 	default:
 		return errors.New("Unrecognised task name!")
 	}
-	for _, dest := range destPlatforms {
+	//0.11.x platforms are archived concurrently
+	return runPlatformJobs(tp, taskName, jobsPerPlatform(destPlatforms), func(job platformJob, logger *log.Logger) error {
+		dest := job.Platform
+		//0.11.x incremental builds
+		if tp.state.isUpToDate(taskName, job) {
+			logger.Printf("Up to date. Skipping (use -rebuild to archive anyway)")
+			return nil
+		}
+		err := ensureExes(logger, tp, dest)
+		if err != nil {
+			return err
+		}
 		isIncludeTopLevelDir := platforms.ContainsPlatform(destPlatformsTopLevelDir, dest)
-		err := archivePlat(dest.Os, dest.Arch, tp.MainDirs, tp.AppName, tp.WorkingDirectory, tp.OutDestRoot, tp.Settings, ending, archiver, isIncludeTopLevelDir)
+		//0.11.x binaries come from the artifact manifest
+		exes := []string{}
+		for _, mainDir := range tp.packagedMainDirsFor(dest) {
+			exes = append(exes, tp.binaryPath(dest, mainDir))
+		}
+		archivePath, err := archivePlat(logger, dest, exes, tp.AppName, tp.WorkingDirectory, tp.OutDestRoot, tp.Settings.ForPlatform(dest), ending, archiver, isIncludeTopLevelDir)
 		if err != nil {
-			//TODO - 'force' option?
 			return err
 		}
-	}
-	//TODO return error?
-	return nil
+		tp.state.record(taskName, job, []string{archivePath})
+		return tp.artifacts.register(ARTIFACT_ARCHIVE, taskName, dest, "", archivePath)
+	})
 }
 
-func archivePlat(goos, arch string, mainDirs []string, appName, workingDirectory, outDestRoot string, settings config.Settings, ending string, archiver archive.Archiver, includeTopLevelDir bool) error {
+func archivePlat(logger *log.Logger, dest platforms.Platform, exes []string, appName, workingDirectory, outDestRoot string, settings config.Settings, ending string, archiver archive.Archiver, includeTopLevelDir bool) (string, error) {
 	resources := core.ParseIncludeResources(workingDirectory, settings.ResourcesInclude, settings.ResourcesExclude, settings.IsVerbose())
-	exes := []string{}
-	for _, mainDir := range mainDirs {
-		exeName := filepath.Base(mainDir)
-		relativeBin := core.GetRelativeBin(goos, arch, exeName, false, settings.GetFullVersionName())
-		exes = append(exes, filepath.Join(outDestRoot, relativeBin))
-	}
 	outDir := filepath.Join(outDestRoot, settings.GetFullVersionName())
-	err := os.MkdirAll(outDir, 0777)
+	err := core.MkdirAll(outDir, 0777)
 	if err != nil {
-		return err
+		return "", err
 	}
-	archivePath, err := archive.ArchiveBinariesAndResources(outDir, goos+"_"+arch,
+	archivePath, err := archive.ArchiveBinariesAndResources(outDir, dest.Name(),
 		exes, appName, resources, settings, archiver, ending, includeTopLevelDir)
 	if err != nil {
-		log.Printf("ZIP error: %s", err)
-		return err
+		logger.Printf("ZIP error: %s", err)
+		return "", err
 	} else {
-		log.Printf("Artifact(s) archived to %s", archivePath)
+		logger.Printf("Artifact(s) archived to %s", archivePath)
 	}
-	return nil
+	return filepath.Join(outDir, archivePath), nil
 }
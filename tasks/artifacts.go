@@ -0,0 +1,236 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"encoding/json"
+	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/openxo/goxc/core"
+	"github.com/openxo/goxc/platforms"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	ARTIFACTS_FILENAME = "artifacts.json"
+
+	ARTIFACT_BINARY         = "binary"
+	ARTIFACT_ARCHIVE        = "archive"
+	ARTIFACT_PACKAGE        = "package"
+	ARTIFACT_SOURCE_PACKAGE = "source-package"
+	ARTIFACT_RESOURCE       = "resource"
+	ARTIFACT_PAGE           = "page"
+)
+
+// A file produced by a task
+type Artifact struct {
+	Kind    string
+	Task    string
+	Os      string `json:",omitempty"`
+	Arch    string `json:",omitempty"`
+	Variant string `json:",omitempty"`
+	MainDir string `json:",omitempty"`
+	//relative to the version dir, using forward slashes
+	Path   string
+	Size   int64  `json:",omitempty"`
+	Sha256 string `json:",omitempty"`
+}
+
+// Artifact manifest (0.11.x)
+// Producer tasks register their artifacts here, and consumer tasks read it, instead of walking the output directory.
+// It's kept in the version dir, so that artifacts from earlier runs (e.g. skipped as up-to-date) are still known.
+type artifactManifest struct {
+	lock       sync.Mutex
+	versionDir string
+	changed    bool
+	Artifacts  []Artifact
+}
+
+// Loads the manifest from the version dir. A missing or corrupt manifest just means 'no artifacts yet'.
+// Artifacts whose files no longer exist (e.g. deleted by hand) are dropped.
+func loadArtifactManifest(versionDir string) *artifactManifest {
+	manifest := &artifactManifest{versionDir: versionDir, Artifacts: []Artifact{}}
+	data, err := ioutil.ReadFile(manifest.path())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Could not read artifact manifest (%v)", err)
+		}
+		return manifest
+	}
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		log.Printf("Could not parse artifact manifest (%v)", err)
+		manifest.Artifacts = []Artifact{}
+	}
+	manifest.pruneMissing()
+	return manifest
+}
+
+// Drops artifacts whose files no longer exist
+func (m *artifactManifest) pruneMissing() {
+	remaining := []Artifact{}
+	for _, artifact := range m.Artifacts {
+		exists, err := core.FileExists(m.fullPath(artifact))
+		if err == nil && !exists {
+			log.Printf("Artifact %s no longer exists. Removing it from the manifest", artifact.Path)
+			m.changed = true
+			continue
+		}
+		remaining = append(remaining, artifact)
+	}
+	m.Artifacts = remaining
+}
+
+func (m *artifactManifest) path() string {
+	return filepath.Join(m.versionDir, ARTIFACTS_FILENAME)
+}
+
+// the absolute path of an artifact
+func (m *artifactManifest) fullPath(artifact Artifact) string {
+	return filepath.Join(m.versionDir, filepath.FromSlash(artifact.Path))
+}
+
+// Registers an artifact (replacing any previous artifact with the same path), given its absolute path.
+// Size & checksum are calculated here (except in a dry run), and an 'artifact' event is emitted.
+func (m *artifactManifest) register(kind, taskName string, dest platforms.Platform, mainDir, fullPath string) error {
+	if m == nil {
+		return nil
+	}
+	relativePath, err := filepath.Rel(m.versionDir, fullPath)
+	if err != nil {
+		return err
+	}
+	artifact := Artifact{Kind: kind, Task: taskName, Os: dest.Os, Arch: dest.Arch, Variant: dest.Variant, MainDir: mainDir, Path: filepath.ToSlash(relativePath)}
+	if !core.IsDryRun() {
+		artifact.Size, artifact.Sha256, err = core.FileSizeAndSha256(fullPath)
+		if err != nil {
+			return err
+		}
+	}
+	m.lock.Lock()
+	m.Artifacts = append(m.without(artifact.Path), artifact)
+	m.changed = true
+	m.lock.Unlock()
+	platform := ""
+	if dest.Os != "" {
+		platform = platformJob{dest, ""}.String()
+	}
+	core.EmitEvent(core.Event{Type: core.EVENT_ARTIFACT, Task: taskName, Platform: platform, MainDir: mainDir, Path: fullPath, Size: artifact.Size, Sha256: artifact.Sha256})
+	return nil
+}
+
+// Unregisters an artifact (e.g. once deleted), given its absolute path
+func (m *artifactManifest) unregister(fullPath string) {
+	if m == nil {
+		return
+	}
+	relativePath, err := filepath.Rel(m.versionDir, fullPath)
+	if err != nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	remaining := m.without(filepath.ToSlash(relativePath))
+	if len(remaining) < len(m.Artifacts) {
+		m.Artifacts = remaining
+		m.changed = true
+	}
+}
+
+// Forgets all artifacts (e.g. once the version dir is deleted)
+func (m *artifactManifest) clear() {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.Artifacts = []Artifact{}
+	m.changed = false
+}
+
+// call with lock held
+func (m *artifactManifest) without(relativePath string) []Artifact {
+	ret := []Artifact{}
+	for _, artifact := range m.Artifacts {
+		if artifact.Path != relativePath {
+			ret = append(ret, artifact)
+		}
+	}
+	return ret
+}
+
+// artifacts of the given kinds (all kinds if none are given), sorted by path
+func (m *artifactManifest) list(kinds ...string) []Artifact {
+	ret := []Artifact{}
+	if m == nil {
+		return ret
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, artifact := range m.Artifacts {
+		if len(kinds) == 0 || core.ContainsString(kinds, artifact.Kind) {
+			ret = append(ret, artifact)
+		}
+	}
+	sort.Sort(artifactsByPath(ret))
+	return ret
+}
+
+// The binary built for the given platform & main dir.
+// Falls back to the conventional location, for binaries built before the manifest existed.
+func (m *artifactManifest) binaryPath(dest platforms.Platform, mainDir, exeName, outDestRoot, fullVersionName string) string {
+	for _, artifact := range m.list(ARTIFACT_BINARY) {
+		if artifact.Os == dest.Os && artifact.Arch == dest.Arch && artifact.Variant == dest.Variant && artifact.MainDir == mainDir {
+			return m.fullPath(artifact)
+		}
+	}
+	return filepath.Join(outDestRoot, core.GetRelativeBin(dest.Os, dest.ArchName(), exeName, false, fullVersionName))
+}
+
+// Writes the manifest, if anything changed
+func (m *artifactManifest) save() error {
+	if m == nil {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if !m.changed {
+		return nil
+	}
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	err = core.MkdirAll(m.versionDir, 0777)
+	if err != nil {
+		return err
+	}
+	m.changed = false
+	return core.WriteFile(m.path(), data, 0644)
+}
+
+type artifactsByPath []Artifact
+
+func (a artifactsByPath) Len() int           { return len(a) }
+func (a artifactsByPath) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a artifactsByPath) Less(i, j int) bool { return strings.ToLower(a[i].Path) < strings.ToLower(a[j].Path) }
@@ -0,0 +1,79 @@
+package tasks
+
+import (
+	"github.com/openxo/goxc/platforms"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArtifactManifest(t *testing.T) {
+	versionDir, err := ioutil.TempDir("", "goxc-artifacts")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(versionDir)
+	linux := platforms.Platform{Os: platforms.LINUX, Arch: platforms.AMD64}
+	binPath := filepath.Join(versionDir, "linux_amd64", "app")
+	os.MkdirAll(filepath.Dir(binPath), 0755)
+	ioutil.WriteFile(binPath, []byte("binary"), 0755)
+	ioutil.WriteFile(filepath.Join(versionDir, "app.tar.gz"), []byte("archive"), 0644)
+
+	manifest := loadArtifactManifest(versionDir)
+	if err := manifest.register(ARTIFACT_BINARY, TASK_XC, linux, "/src/app", binPath); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := manifest.register(ARTIFACT_ARCHIVE, TASK_ARCHIVE_TAR_GZ, linux, "", filepath.Join(versionDir, "app.tar.gz")); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := manifest.save(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	loaded := loadArtifactManifest(versionDir)
+	if len(loaded.list()) != 2 {
+		t.Fatalf("Expected 2 artifacts, got %v", loaded.list())
+	}
+	archives := loaded.list(ARTIFACT_ARCHIVE)
+	if len(archives) != 1 || archives[0].Path != "app.tar.gz" || archives[0].Size != 7 || archives[0].Sha256 == "" {
+		t.Errorf("Unexpected archives %+v", archives)
+	}
+	if loaded.binaryPath(linux, "/src/app", "app", "", "") != binPath {
+		t.Errorf("Unexpected binary path %s", loaded.binaryPath(linux, "/src/app", "app", "", ""))
+	}
+	loaded.unregister(binPath)
+	if len(loaded.list(ARTIFACT_BINARY)) != 0 {
+		t.Errorf("Binary should have been unregistered")
+	}
+	//falls back to the conventional location
+	expected := filepath.Join("out", "1.0", "linux_amd64", "app")
+	if loaded.binaryPath(linux, "/src/app", "app", "out", "1.0") != expected {
+		t.Errorf("Expected %s, got %s", expected, loaded.binaryPath(linux, "/src/app", "app", "out", "1.0"))
+	}
+}
+
+func TestArtifactManifestPrunesMissingFiles(t *testing.T) {
+	versionDir, err := ioutil.TempDir("", "goxc-artifacts")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(versionDir)
+	linux := platforms.Platform{Os: platforms.LINUX, Arch: platforms.AMD64}
+	for _, name := range []string{"app.zip", "app.tar.gz"} {
+		ioutil.WriteFile(filepath.Join(versionDir, name), []byte(name), 0644)
+	}
+	manifest := loadArtifactManifest(versionDir)
+	manifest.register(ARTIFACT_ARCHIVE, TASK_ARCHIVE_ZIP, linux, "", filepath.Join(versionDir, "app.zip"))
+	manifest.register(ARTIFACT_ARCHIVE, TASK_ARCHIVE_TAR_GZ, linux, "", filepath.Join(versionDir, "app.tar.gz"))
+	if err := manifest.save(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	os.Remove(filepath.Join(versionDir, "app.zip"))
+
+	loaded := loadArtifactManifest(versionDir)
+	archives := loaded.list()
+	if len(archives) != 1 || archives[0].Path != "app.tar.gz" {
+		t.Errorf("Expected only app.tar.gz, got %+v", archives)
+	}
+}
@@ -0,0 +1,125 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/openxo/goxc/config"
+	"github.com/openxo/goxc/core"
+	"github.com/openxo/goxc/platforms"
+	"path/filepath"
+	"sort"
+)
+
+// Per-binary settings (0.11.x)
+// Each main dir can have its own name, BuildSettings, platforms & packaging (see config.BinarySettings).
+
+// a main dir relative to the working directory, with forward slashes. '.' for the working directory itself
+func (tp TaskParams) relativeMainDir(mainDir string) string {
+	workingDirectory, err := filepath.Abs(tp.WorkingDirectory)
+	if err != nil {
+		return filepath.ToSlash(mainDir)
+	}
+	mainDirAbs, err := filepath.Abs(mainDir)
+	if err != nil {
+		return filepath.ToSlash(mainDir)
+	}
+	relative, err := filepath.Rel(workingDirectory, mainDirAbs)
+	if err != nil {
+		return filepath.ToSlash(mainDir)
+	}
+	return config.BinaryKey(relative)
+}
+
+func (tp TaskParams) binarySettings(mainDir string) config.BinarySettings {
+	return tp.Settings.GetBinarySettings(tp.relativeMainDir(mainDir))
+}
+
+// the executable's name for a main dir (without any '.exe')
+func (tp TaskParams) exeName(mainDir string) string {
+	binary := tp.binarySettings(mainDir)
+	if binary.Name != "" {
+		return binary.Name
+	}
+	return filepath.Base(mainDir)
+}
+
+// the settings for building a main dir for a platform. The binary's BuildSettings take priority over any PlatformOverrides
+func (tp TaskParams) settingsFor(dest platforms.Platform, mainDir string) config.Settings {
+	return tp.Settings.ForPlatform(dest).ForBinary(tp.relativeMainDir(mainDir))
+}
+
+// whether the main dir is built for the platform, as per the binary's BuildConstraints
+func (tp TaskParams) isBinaryBuiltFor(mainDir string, dest platforms.Platform) bool {
+	binary := tp.binarySettings(mainDir)
+	return binary.BuildConstraints == "" || platforms.MatchesBuildConstraints(binary.BuildConstraints, dest)
+}
+
+// the main dirs built for the platform
+func (tp TaskParams) mainDirsFor(dest platforms.Platform) []string {
+	mainDirs := []string{}
+	for _, mainDir := range tp.MainDirs {
+		if tp.isBinaryBuiltFor(mainDir, dest) {
+			mainDirs = append(mainDirs, mainDir)
+		}
+	}
+	return mainDirs
+}
+
+// the main dirs built for the platform & included in archives & packages
+func (tp TaskParams) packagedMainDirsFor(dest platforms.Platform) []string {
+	mainDirs := []string{}
+	for _, mainDir := range tp.mainDirsFor(dest) {
+		if tp.binarySettings(mainDir).IsPackaged() {
+			mainDirs = append(mainDirs, mainDir)
+		}
+	}
+	return mainDirs
+}
+
+// one job per platform, per main dir built for that platform
+func (tp TaskParams) binaryJobs(destPlatforms []platforms.Platform) []platformJob {
+	jobs := []platformJob{}
+	for _, dest := range destPlatforms {
+		jobs = append(jobs, jobsPerPlatformAndMainDir([]platforms.Platform{dest}, tp.mainDirsFor(dest))...)
+	}
+	return jobs
+}
+
+// the path of the binary for a main dir & platform
+func (tp TaskParams) binaryPath(dest platforms.Platform, mainDir string) string {
+	return tp.artifacts.binaryPath(dest, mainDir, tp.exeName(mainDir), tp.OutDestRoot, tp.Settings.GetFullVersionName())
+}
+
+// Warns about Binaries which don't match any main dir (probably a typo)
+func warnUnmatchedBinaries(tp TaskParams) {
+	relativeMainDirs := map[string]bool{}
+	for _, mainDir := range tp.MainDirs {
+		relativeMainDirs[tp.relativeMainDir(mainDir)] = true
+	}
+	unmatched := []string{}
+	for key := range tp.Settings.Binaries {
+		if !relativeMainDirs[config.BinaryKey(key)] {
+			unmatched = append(unmatched, key)
+		}
+	}
+	sort.Strings(unmatched)
+	for _, key := range unmatched {
+		core.Warnf("Binaries: '%s' does not match any main dir", key)
+	}
+}
@@ -0,0 +1,36 @@
+package tasks
+
+import (
+	"github.com/openxo/goxc/config"
+	"github.com/openxo/goxc/platforms"
+	"path/filepath"
+	"testing"
+)
+
+func TestBinaryJobs(t *testing.T) {
+	wd := filepath.Join("src", "app")
+	isPackaged := false
+	tp := TaskParams{
+		MainDirs:         []string{filepath.Join(wd, "cmd", "server"), filepath.Join(wd, "cmd", "debug"), wd},
+		WorkingDirectory: wd,
+		Settings: config.Settings{Binaries: map[string]config.BinarySettings{
+			"cmd/server": config.BinarySettings{Name: "app-server"},
+			"cmd/debug":  config.BinarySettings{BuildConstraints: "linux", Packaged: &isPackaged}}}}
+	linux := platforms.Platform{Os: platforms.LINUX, Arch: platforms.AMD64}
+	windows := platforms.Platform{Os: platforms.WINDOWS, Arch: platforms.AMD64}
+	jobs := tp.binaryJobs([]platforms.Platform{linux, windows})
+	if len(jobs) != 5 {
+		t.Fatalf("Expected 5 jobs (the debug tool is linux only), got %v", jobs)
+	}
+	for _, job := range jobs {
+		if job.Platform == windows && job.MainDir == tp.MainDirs[1] {
+			t.Errorf("Debug tool should not be built for windows")
+		}
+	}
+	if len(tp.packagedMainDirsFor(linux)) != 2 {
+		t.Errorf("Debug tool should not be packaged: %v", tp.packagedMainDirsFor(linux))
+	}
+	if tp.exeName(tp.MainDirs[0]) != "app-server" || tp.exeName(tp.MainDirs[1]) != "debug" || tp.exeName(wd) != "app" {
+		t.Errorf("Unexpected exe names %s, %s, %s", tp.exeName(tp.MainDirs[0]), tp.exeName(tp.MainDirs[1]), tp.exeName(wd))
+	}
+}
@@ -25,12 +25,14 @@ import (
 	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
 	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
 	"github.com/openxo/goxc/core"
+	"github.com/openxo/goxc/platforms"
 	"github.com/openxo/goxc/typeutils"
 	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -44,7 +46,7 @@ func init() {
 		TASK_BINTRAY,
 		"Upload artifacts to bintray.com, and generate a local markdown page of links (bintray registration details required in goxc config. See `goxc -h bintray`)",
 		runTaskBintray,
-		map[string]interface{}{"subject": "", "apikey": "", "repository": "",
+		map[string]interface{}{"subject": "", "apikey": "", "repository": "", "package": "",
 			"apihost":       "https://api.bintray.com/",
 			"downloadshost": "https://dl.bintray.com/",
 			"downloadspage": "bintray.md",
@@ -68,7 +70,8 @@ Files hosted at [bintray.com](https://bintray.com)
 
 {{.ExtraVars.footer}}`,
 			"templateFile":      "", //use if populated
-			"templateExtraVars": map[string]interface{}{"footer": "Generated by goxc"}}})
+			"templateExtraVars": map[string]interface{}{"footer": "Generated by goxc"}},
+		[]string{TASKALIAS_ARCHIVE, TASK_PKG_BUILD}})
 }
 
 type BtDownload struct {
@@ -89,7 +92,6 @@ func runTaskBintray(tp TaskParams) error {
 	pkg := tp.Settings.GetTaskSettingString(TASK_BINTRAY, "package")
 	apiHost := tp.Settings.GetTaskSettingString(TASK_BINTRAY, "apihost")
 	//downloadsHost := tp.Settings.GetTaskSettingString(TASK_BINTRAY, "downloadshost")
-	versionDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
 
 	missing := []string{}
 
@@ -127,17 +129,18 @@ func runTaskBintray(tp TaskParams) error {
 	}
 	templateVars := tp.Settings.GetTaskSettingMap(TASK_DOWNLOADS_PAGE, "templateExtraVars")
 	reportFilename := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName(), outFilename)
-	_, err := os.Stat(filepath.Dir(reportFilename))
-	if err != nil {
-		if os.IsNotExist(err) {
-			return errors.New("No artifacts built for this version yet. Please build some artifacts before running the 'bintray' task")
+	//0.11.x artifacts come from the manifest, rather than walking the version dir
+	artifacts := tp.artifacts.list(ARTIFACT_BINARY, ARTIFACT_ARCHIVE, ARTIFACT_PACKAGE, ARTIFACT_SOURCE_PACKAGE)
+	if len(artifacts) == 0 {
+		if core.IsDryRun() {
+			//in a dry run, the artifacts may not exist yet
+			log.Printf("No artifacts built for this version yet. Nothing to upload")
 		} else {
-			return err
+			return errors.New("No artifacts built for this version yet. Please build some artifacts before running the 'bintray' task")
 		}
 	}
 	report := BtReport{tp.AppName, tp.Settings.GetFullVersionName(), map[string]*[]BtDownload{}, templateVars}
-	flags := os.O_WRONLY | os.O_TRUNC | os.O_CREATE
-	out, err := os.OpenFile(reportFilename, flags, 0600)
+	out, err := core.CreateFile(reportFilename, 0600)
 	if err != nil {
 		return err
 	}
@@ -152,25 +155,25 @@ func runTaskBintray(tp TaskParams) error {
 	//	}
 	//for 'first entry in dir' detection.
 	dirs := []string{}
-	err = filepath.Walk(versionDir, func(path string, info os.FileInfo, e error) error {
-		return walkFunc(path, info, e, outFilename, dirs, tp, format, report)
-	})
-	if err != nil {
-		return err
+	for _, artifact := range artifacts {
+		err = uploadArtifact(artifact, tp.artifacts.fullPath(artifact), dirs, tp, format, report)
+		if err != nil {
+			return err
+		}
 	}
 	err = runTemplate(reportFilename, templateFile, templateText, out, report, format)
 	if err != nil {
 		return err
 	}
 	//close explicitly for return value
-	return out.Close()
+	err = out.Close()
+	if err != nil {
+		return err
+	}
+	return tp.artifacts.register(ARTIFACT_PAGE, TASK_BINTRAY, platforms.Platform{}, "", reportFilename)
 }
 
-func walkFunc(fullPath string, fi2 os.FileInfo, err error, reportFilename string, dirs []string, tp TaskParams, format string, report BtReport) error {
-	if fi2.IsDir() || fi2.Name() == reportFilename {
-
-		return nil
-	}
+func uploadArtifact(artifact Artifact, fullPath string, dirs []string, tp TaskParams, format string, report BtReport) error {
 	subject := tp.Settings.GetTaskSettingString(TASK_BINTRAY, "subject")
 	apikey := tp.Settings.GetTaskSettingString(TASK_BINTRAY, "apikey")
 	repository := tp.Settings.GetTaskSettingString(TASK_BINTRAY, "repository")
@@ -179,11 +182,11 @@ func walkFunc(fullPath string, fi2 os.FileInfo, err error, reportFilename string
 	downloadsHost := tp.Settings.GetTaskSettingString(TASK_BINTRAY, "downloadshost")
 	includeResources := tp.Settings.GetTaskSettingString(TASK_BINTRAY, "include")
 	excludeResources := tp.Settings.GetTaskSettingString(TASK_BINTRAY, "exclude")
-	versionDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
-
-	relativePath := strings.Replace(fullPath, versionDir, "", -1)
-	relativePath = strings.TrimPrefix(relativePath, "/")
-	fmt.Printf("relative path %s, full path %s\n", relativePath, fullPath)
+	relativePath := artifact.Path
+	fileName := path.Base(relativePath)
+	if tp.Settings.IsVerbose() {
+		log.Printf("relative path %s, full path %s", relativePath, fullPath)
+	}
 
 	resourceGlobs := core.ParseCommaGlobs(includeResources)
 	//log.Printf("IncludeGlobs: %v", resourceGlobs)
@@ -191,7 +194,7 @@ func walkFunc(fullPath string, fi2 os.FileInfo, err error, reportFilename string
 	//log.Printf("ExcludeGlobs: %v", excludeGlobs)
 	matches := false
 	for _, resourceGlob := range resourceGlobs {
-		ok, err := filepath.Match(resourceGlob, fi2.Name())
+		ok, err := filepath.Match(resourceGlob, fileName)
 		if err != nil {
 			return err
 		}
@@ -204,7 +207,7 @@ func walkFunc(fullPath string, fi2 os.FileInfo, err error, reportFilename string
 		return nil
 	}
 	for _, excludeGlob := range excludeGlobs {
-		ok, err := filepath.Match(excludeGlob, fi2.Name())
+		ok, err := filepath.Match(excludeGlob, fileName)
 		if err != nil {
 			return err
 		}
@@ -227,7 +230,7 @@ func walkFunc(fullPath string, fi2 os.FileInfo, err error, reportFilename string
 		dirs = append(dirs, parent)
 	}
 	//fmt.Printf("relative path %s, platform %s\n", relativePath, parent)
-	text := fi2.Name()
+	text := fileName
 	/*
 		text := strings.Replace(fi2.Name(), "_", "\\_", -1)
 		if strings.HasSuffix(fi2.Name(), ".zip") {
@@ -271,7 +274,7 @@ func walkFunc(fullPath string, fi2 os.FileInfo, err error, reportFilename string
 	if format == "markdown" {
 		text = strings.Replace(text, "_", "\\_", -1)
 	}
-	category := getCategory(relativePath)
+	category := getCategory(artifact)
 	download := BtDownload{text, downloadsUrl}
 	v, ok := report.Categories[category]
 	var existing []BtDownload
@@ -342,7 +345,22 @@ func (e httpError) Error() string {
 	return fmt.Sprintf("Error code: %d, message: %s", e.statusCode, e.message)
 }
 
+// 0.11.x via core.RunOp, so that a dry run just records the request
 func doHttp(method, url, subject, apikey string, requestReader io.Reader, requestLength int64) (map[string]interface{}, error) {
+	var b map[string]interface{}
+	details := []string{}
+	if requestLength > 0 {
+		details = append(details, "Content-Length: "+strconv.FormatInt(requestLength, 10))
+	}
+	err := core.RunOp(core.Op{Kind: core.OP_HTTP, Description: method + " " + url, Details: details}, func() error {
+		var err error
+		b, err = doHttpRequest(method, url, subject, apikey, requestReader, requestLength)
+		return err
+	})
+	return b, err
+}
+
+func doHttpRequest(method, url, subject, apikey string, requestReader io.Reader, requestLength int64) (map[string]interface{}, error) {
 	client := &http.Client{}
 	req, err := http.NewRequest(method, url, requestReader)
 	if err != nil {
@@ -383,8 +401,18 @@ func doHttp(method, url, subject, apikey string, requestReader io.Reader, reques
 }
 
 func getVersions(apihost, apikey, subject, repository, pkg string) ([]string, error) {
-	client := &http.Client{}
 	url := apihost + "/packages/" + subject + "/" + repository + "/" + pkg
+	var versions []string
+	err := core.RunOp(core.Op{Kind: core.OP_HTTP, Description: "GET " + url}, func() error {
+		var err error
+		versions, err = getVersionsRequest(url, apikey, subject)
+		return err
+	})
+	return versions, err
+}
+
+func getVersionsRequest(url, apikey, subject string) ([]string, error) {
+	client := &http.Client{}
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
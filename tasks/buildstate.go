@@ -0,0 +1,312 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/openxo/goxc/config"
+	"github.com/openxo/goxc/core"
+	"github.com/openxo/goxc/executils"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	BUILD_STATE_FILENAME = ".goxc-state.json"
+)
+
+// Incremental builds (0.11.x)
+// The build state records the inputs of each successful unit of work (task x platform x main dir), as a hash.
+// Inputs are the source files, the (relevant) settings, the environment and the toolchain version.
+// Work is skipped when its inputs match the last successful run, and its outputs are still there.
+type buildState struct {
+	lock    sync.Mutex
+	path    string
+	rebuild bool
+	changed bool
+	Entries map[string]*buildStateEntry
+
+	inputsOnce    sync.Once
+	workingDir    string
+	outDestRoot   string
+	settings      config.Settings
+	commonInputs  string
+	commonInputsE error
+}
+
+type buildStateEntry struct {
+	Inputs  string
+	Outputs []string `json:",omitempty"`
+	//outputs have been deliberately removed since (by 'rmbin')
+	OutputsRemoved bool `json:",omitempty"`
+}
+
+// Loads the build state from the output directory. A missing or corrupt state file just means 'rebuild everything'.
+func loadBuildState(workingDirectory, outDestRoot string, settings config.Settings) *buildState {
+	state := &buildState{
+		path:        filepath.Join(outDestRoot, BUILD_STATE_FILENAME),
+		rebuild:     settings.Rebuild,
+		Entries:     map[string]*buildStateEntry{},
+		workingDir:  workingDirectory,
+		outDestRoot: outDestRoot,
+		settings:    settings,
+	}
+	data, err := ioutil.ReadFile(state.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Could not read build state (%v). Rebuilding everything", err)
+		}
+		return state
+	}
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		log.Printf("Could not parse build state (%v). Rebuilding everything", err)
+		state.Entries = map[string]*buildStateEntry{}
+	}
+	return state
+}
+
+func stateKey(taskName string, job platformJob) string {
+	return taskName + "|" + job.String() + "|" + job.MainDir
+}
+
+// the hash of all inputs for the given task & job.
+func (s *buildState) inputs(taskName string, job platformJob) (string, error) {
+	s.inputsOnce.Do(func() {
+		s.commonInputs, s.commonInputsE = s.hashCommonInputs()
+	})
+	if s.commonInputsE != nil {
+		return "", s.commonInputsE
+	}
+	return hashStrings([]string{s.commonInputs, stateKey(taskName, job)}), nil
+}
+
+// True if the given work can be skipped.
+func (s *buildState) isUpToDate(taskName string, job platformJob) bool {
+	if s == nil || s.rebuild {
+		return false
+	}
+	inputs, err := s.inputs(taskName, job)
+	if err != nil {
+		log.Printf("Could not determine inputs for %s (%v)", stateKey(taskName, job), err)
+		return false
+	}
+	s.lock.Lock()
+	entry, keyExists := s.Entries[stateKey(taskName, job)]
+	s.lock.Unlock()
+	if !keyExists || entry.Inputs != inputs {
+		return false
+	}
+	if entry.OutputsRemoved {
+		return true
+	}
+	for _, output := range entry.Outputs {
+		if exists, _ := core.FileExists(output); !exists {
+			return false
+		}
+	}
+	return true
+}
+
+// Records successful work
+func (s *buildState) record(taskName string, job platformJob, outputs []string) {
+	if s == nil {
+		return
+	}
+	inputs, err := s.inputs(taskName, job)
+	if err != nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Entries[stateKey(taskName, job)] = &buildStateEntry{Inputs: inputs, Outputs: outputs}
+	s.changed = true
+}
+
+// Records that the outputs of some work were deliberately removed
+func (s *buildState) recordRemoved(taskName string, job platformJob) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if entry, keyExists := s.Entries[stateKey(taskName, job)]; keyExists {
+		entry.OutputsRemoved = true
+		s.changed = true
+	}
+}
+
+// Writes the state file, if anything changed
+func (s *buildState) save() error {
+	if s == nil {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.changed {
+		return nil
+	}
+	data, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+	err = core.MkdirAll(s.outDestRoot, 0777)
+	if err != nil {
+		return err
+	}
+	s.changed = false
+	return core.WriteFile(s.path, data, 0644)
+}
+
+// hash of sources, settings, env & toolchain version. These are the same for all tasks in a run.
+func (s *buildState) hashCommonInputs() (string, error) {
+	sources, err := hashSources(s.workingDir, s.outDestRoot)
+	if err != nil {
+		return "", err
+	}
+	settingsHash, err := hashSettings(s.settings)
+	if err != nil {
+		return "", err
+	}
+	toolchain, err := executils.GoVersion(s.settings.GoRoot)
+	if err != nil {
+		//not fatal. Use the runtime version
+		toolchain = runtime.Version()
+	}
+	return hashStrings([]string{sources, settingsHash, hashStrings(getBuildEnv(s.settings)), toolchain}), nil
+}
+
+func hashStrings(items []string) string {
+	h := sha256.New()
+	for _, item := range items {
+		fmt.Fprintf(h, "%d:%s\n", len(item), item)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashes all (non-hidden) files in the working directory, except for output dirs.
+// When the working directory is below its module root, go.mod, go.sum & the vendor dir of the module root are hashed too.
+// Dependencies elsewhere (GOPATH, the module cache) are not.
+func hashSources(workingDirectory, outDestRoot string) (string, error) {
+	h := sha256.New()
+	workingDirectory, err := filepath.Abs(workingDirectory)
+	if err != nil {
+		return "", err
+	}
+	err = hashTree(h, workingDirectory, workingDirectory, outDestRoot)
+	if err != nil {
+		return "", err
+	}
+	moduleRoot := core.GetModuleRoot(workingDirectory)
+	if moduleRoot != "" && moduleRoot != workingDirectory {
+		for _, name := range []string{core.GOMOD_FILENAME, "go.sum"} {
+			err = hashFile(h, moduleRoot, filepath.Join(moduleRoot, name))
+			if err != nil && !os.IsNotExist(err) {
+				return "", err
+			}
+		}
+		vendorDir := filepath.Join(moduleRoot, "vendor")
+		if fi, err := os.Stat(vendorDir); err == nil && fi.IsDir() && !strings.HasPrefix(workingDirectory, vendorDir+string(filepath.Separator)) {
+			err = hashTree(h, moduleRoot, vendorDir, outDestRoot)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashTree(h io.Writer, baseDir, root, outDestRoot string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			if absOutDestRoot, err := filepath.Abs(outDestRoot); err == nil && path == absOutDestRoot {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return hashFile(h, baseDir, path)
+	})
+}
+
+// the path (relative to baseDir) and then the content
+func hashFile(h io.Writer, baseDir, path string) error {
+	relPath, err := filepath.Rel(baseDir, path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fmt.Fprintf(h, "%s\n", filepath.ToSlash(relPath))
+	_, err = io.Copy(h, f)
+	return err
+}
+
+// settings which don't affect the outcome of any task are left out
+func hashSettings(settings config.Settings) (string, error) {
+	settings.Tasks = nil
+	settings.TasksAppend = nil
+	settings.TasksPrepend = nil
+	settings.TasksExclude = nil
+	settings.Aliases = nil
+	settings.Verbosity = ""
+	settings.Parallelism = 0
+	settings.KeepGoing = false
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return "", err
+	}
+	return hashStrings([]string{string(data)}), nil
+}
+
+// env vars which can affect a build
+func getBuildEnv(settings config.Settings) []string {
+	env := append([]string{}, settings.Env...)
+	for _, envItem := range os.Environ() {
+		if strings.HasPrefix(envItem, "GO") || strings.HasPrefix(envItem, "CGO_") || strings.HasPrefix(envItem, "CC=") || strings.HasPrefix(envItem, "CXX=") {
+			env = append(env, envItem)
+		}
+	}
+	sort.Strings(env)
+	return env
+}
@@ -36,11 +36,13 @@ func init() {
 		"bump package version in .goxc.json. By default, the patch number (after the second dot) is increased by one. You can specify major or minor instead with -dot=0 or -dot=1",
 		bump,
 		map[string]interface{}{
-			"dot": "2"}})
+			"dot": "2"},
+		nil})
 }
 
 func bump(tp TaskParams) error {
-	c, err := config.LoadJsonConfigs(tp.WorkingDirectory, []string{core.GOXC_CONFIGNAME_BASE + core.GOXC_FILE_EXT}, tp.Settings.IsVerbose())
+	//0.11.x the config is written back, so don't flatten any parents into it
+	c, err := config.LoadJsonConfigsWithoutExtends(tp.WorkingDirectory, []string{core.GOXC_CONFIGNAME_BASE + core.GOXC_FILE_EXT}, tp.Settings.IsVerbose())
 	if err != nil {
 		return nil
 	}
@@ -0,0 +1,106 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"fmt"
+	"github.com/openxo/goxc/config"
+	"github.com/openxo/goxc/platforms"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// cgo cross-compilation (0.11.x)
+// The C toolchain comes from the 'Cgo' settings, normally set per platform in PlatformOverrides.
+
+// The cgo environment for building a platform. Reports whether cgo is enabled, and validates the C toolchain if so.
+func cgoEnv(logger *log.Logger, dest platforms.Platform, settings config.Settings) ([]string, error) {
+	cgo := config.CgoSettings{}
+	if settings.Cgo != nil {
+		cgo = *settings.Cgo
+	}
+	isEnabled, isSet := cgo.IsEnabled()
+	if !isSet {
+		//CGO_ENABLED from Env or the environment
+		if value, found := getEnvValue(settings.Env, "CGO_ENABLED"); found {
+			if value == "0" {
+				logger.Printf("cgo is disabled for %s (CGO_ENABLED=0)", dest.Name())
+			}
+			return cgo.Env(), nil
+		}
+		if dest.Os != runtime.GOOS || dest.Arch != runtime.GOARCH {
+			logger.Printf("cgo is disabled for %s: cross-compiling without a C compiler (set Cgo.CC in PlatformOverrides to enable it)", dest.Name())
+		}
+		return cgo.Env(), nil
+	}
+	if !isEnabled {
+		logger.Printf("cgo is disabled for %s (Cgo.Enabled is false)", dest.Name())
+		return cgo.Env(), nil
+	}
+	if info, found := platforms.GetPlatformInfo(dest); found && !info.CgoSupported {
+		return nil, fmt.Errorf("cgo is enabled, but not supported for %s", dest.Name())
+	}
+	if settings.GetTaskSettingBool(TASK_XC, "validateCgo") {
+		err := validateCgo(cgo)
+		if err != nil {
+			return nil, fmt.Errorf("cgo toolchain for %s: %v", dest.Name(), err)
+		}
+	}
+	cc := cgo.CC
+	if cc == "" {
+		cc = "the default C compiler"
+		if dest.Os != runtime.GOOS || dest.Arch != runtime.GOARCH {
+			cc += " (which probably targets this machine, not the target platform. Set Cgo.CC)"
+		}
+	}
+	logger.Printf("cgo is enabled for %s, using %s", dest.Name(), cc)
+	return cgo.Env(), nil
+}
+
+// Checks that the compilers & sysroot exist
+func validateCgo(cgo config.CgoSettings) error {
+	for _, compiler := range []string{cgo.CC, cgo.CXX} {
+		if compiler == "" {
+			continue
+		}
+		fields := strings.Fields(compiler)
+		if _, err := exec.LookPath(fields[0]); err != nil {
+			return fmt.Errorf("compiler '%s' not found (%v)", fields[0], err)
+		}
+	}
+	if cgo.Sysroot != "" {
+		if fi, err := os.Stat(cgo.Sysroot); err != nil || !fi.IsDir() {
+			return fmt.Errorf("sysroot '%s' is not a directory", cgo.Sysroot)
+		}
+	}
+	return nil
+}
+
+// The value of an env var: the last occurrence in env, or else from the environment
+func getEnvValue(env []string, key string) (string, bool) {
+	for i := len(env) - 1; i >= 0; i-- {
+		if strings.HasPrefix(env[i], key+"=") {
+			return strings.TrimPrefix(env[i], key+"="), true
+		}
+	}
+	return os.LookupEnv(key)
+}
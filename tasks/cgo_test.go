@@ -0,0 +1,30 @@
+package tasks
+
+import (
+	"github.com/openxo/goxc/config"
+	"github.com/openxo/goxc/platforms"
+	"io/ioutil"
+	"log"
+	"testing"
+)
+
+func TestCgoEnvValidatesCompiler(t *testing.T) {
+	logger := log.New(ioutil.Discard, "", 0)
+	dest := platforms.Platform{Os: platforms.LINUX, Arch: platforms.ARM}
+	settings := config.Settings{
+		Cgo:          &config.CgoSettings{CC: "goxc-no-such-compiler -target arm"},
+		TaskSettings: map[string]map[string]interface{}{TASK_XC: {"validateCgo": true}}}
+	if _, err := cgoEnv(logger, dest, settings); err == nil {
+		t.Errorf("Expected an error for a missing compiler")
+	}
+	settings.TaskSettings[TASK_XC]["validateCgo"] = false
+	env, err := cgoEnv(logger, dest, settings)
+	if err != nil || len(env) != 2 || env[0] != "CGO_ENABLED=1" {
+		t.Errorf("Unexpected env %v (%v)", env, err)
+	}
+	disabled := false
+	settings.Cgo.Enabled = &disabled
+	if env, err := cgoEnv(logger, dest, settings); err != nil || len(env) != 1 || env[0] != "CGO_ENABLED=0" {
+		t.Errorf("Unexpected env %v (%v)", env, err)
+	}
+}
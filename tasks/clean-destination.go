@@ -17,7 +17,9 @@ package tasks
 */
 
 import (
-	"os"
+	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/openxo/goxc/core"
 	"path/filepath"
 )
 
@@ -27,9 +29,16 @@ func init() {
 		"clean-destination",
 		"Delete the output directory for this version of the artifact.",
 		runTaskCleanDestination,
+		nil,
 		nil})
 }
 
 func runTaskCleanDestination(tp TaskParams) error {
-	return os.RemoveAll(filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName()))
+	err := core.RemoveAll(filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName()))
+	if err != nil {
+		return err
+	}
+	//0.11.x the artifact manifest was in there too
+	tp.artifacts.clear()
+	return nil
 }
@@ -20,11 +20,10 @@ import (
 	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
 	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
 	"github.com/openxo/goxc/config"
-	"github.com/openxo/goxc/core"
+	"github.com/openxo/goxc/executils"
 	"github.com/openxo/goxc/platforms"
 	"log"
 	"os/exec"
-	"path/filepath"
 	"runtime"
 )
 
@@ -32,7 +31,8 @@ var codesignTask = Task{
 	TASK_CODESIGN,
 	"sign code for Mac. Only Mac hosts are supported for this task.",
 	runTaskCodesign,
-	map[string]interface{}{"id": ""}}
+	map[string]interface{}{"id": ""},
+	[]string{TASK_XC}}
 
 //runs automatically
 func init() {
@@ -40,40 +40,38 @@ func init() {
 }
 
 func runTaskCodesign(tp TaskParams) (err error) {
-	for _, dest := range tp.DestPlatforms {
-		for _, mainDir := range tp.MainDirs {
-			exeName := filepath.Base(mainDir)
-			relativeBin := core.GetRelativeBin(dest.Os, dest.Arch, exeName, false, tp.Settings.GetFullVersionName())
-			err = codesignPlat(dest.Os, dest.Arch, tp.OutDestRoot, relativeBin, tp.Settings)
+	//0.11.x binaries are signed concurrently
+	jobs := tp.binaryJobs(tp.DestPlatforms)
+	return runPlatformJobs(tp, TASK_CODESIGN, jobs, func(job platformJob, logger *log.Logger) error {
+		//0.11.x binary location comes from the artifact manifest
+		binPath := tp.binaryPath(job.Platform, job.MainDir)
+		signed, err := codesignPlat(logger, job.Platform.Os, job.Platform.Arch, binPath, tp.Settings)
+		if err != nil || !signed {
+			return err
 		}
-	}
-	//TODO return error
-	return err
+		//the checksum has changed
+		return tp.artifacts.register(ARTIFACT_BINARY, TASK_XC, job.Platform, job.MainDir, binPath)
+	})
 }
 
-func codesignPlat(goos, arch string, outDestRoot string, relativeBin string, settings config.Settings) error {
+// 0.11.x returns true if the binary was signed
+func codesignPlat(logger *log.Logger, goos, arch string, binPath string, settings config.Settings) (bool, error) {
 	// settings.codesign only works on OS X for binaries generated for OS X.
 	id := settings.GetTaskSettingString("codesign", "id")
 	if id != "" && runtime.GOOS == platforms.DARWIN && goos == platforms.DARWIN {
-		if err := signBinary(filepath.Join(outDestRoot, relativeBin), id); err != nil {
-			log.Printf("codesign failed: %s", err)
-			return err
+		if err := signBinary(binPath, id); err != nil {
+			logger.Printf("codesign failed: %s", err)
+			return false, err
 		} else {
-			log.Printf("Signed with ID: %q", id)
-			return nil
+			logger.Printf("Signed with ID: %q", id)
+			return true, nil
 		}
 	}
-	return nil
+	return false, nil
 }
 
 func signBinary(binPath string, id string) error {
 	cmd := exec.Command("codesign")
 	cmd.Args = append(cmd.Args, "-s", id, binPath)
-	if err := cmd.Start(); err != nil {
-		return err
-	}
-	if err := cmd.Wait(); err != nil {
-		return err
-	}
-	return nil
+	return executils.RunCmd(cmd, []string{})
 }
@@ -20,6 +20,7 @@ import (
 	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
 	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
 	"github.com/openxo/goxc/core"
+	"github.com/openxo/goxc/platforms"
 	"io"
 	"log"
 	"os"
@@ -33,6 +34,7 @@ func init() {
 		TASK_COPY_RESOURCES,
 		"Copy resources",
 		runTaskCopyResources,
+		nil,
 		nil})
 }
 
@@ -52,16 +54,19 @@ func runTaskCopyResources(tp TaskParams) error {
 			return err
 		}
 		if finfo.IsDir() {
-			err = os.MkdirAll(destPath, 0777)
+			err = core.MkdirAll(destPath, 0777)
 			if err != nil && !os.IsExist(err) {
 				return err
 			}
 		} else {
-			err = os.MkdirAll(filepath.Dir(destPath), 0777)
+			err = core.MkdirAll(filepath.Dir(destPath), 0777)
 			if err != nil && !os.IsExist(err) {
 				return err
 			}
 			_, err = copyFile(sourcePath, destPath)
+			if err == nil {
+				err = tp.artifacts.register(ARTIFACT_RESOURCE, TASK_COPY_RESOURCES, platforms.Platform{}, "", destPath)
+			}
 		}
 		if err != nil {
 			return err
@@ -72,7 +77,7 @@ func runTaskCopyResources(tp TaskParams) error {
 
 func copyDir(srcDir, destDir string) (fileCount int, err error) {
 	fileCount = 0
-	err = os.MkdirAll(destDir, 0777)
+	err = core.MkdirAll(destDir, 0777)
 	if err != nil && !os.IsExist(err) {
 		return fileCount, err
 	}
@@ -81,12 +86,7 @@ func copyDir(srcDir, destDir string) (fileCount int, err error) {
 		base := strings.Replace(path, srcDir, "", 1)
 		dest := filepath.Join(destDir, base)
 		if fi.IsDir() {
-			err := os.Mkdir(dest, 0777)
-			if os.IsExist(err) {
-				return nil
-			} else {
-				return err
-			}
+			return core.MkdirAll(dest, 0777)
 		} else {
 			log.Printf("path: %s, base: %s", path, base)
 			_, err := copyFile(path, dest)
@@ -104,7 +104,7 @@ func copyFile(srcName, dstName string) (written int64, err error) {
 	}
 	defer src.Close()
 
-	dst, err := os.Create(dstName)
+	dst, err := core.CreateFile(dstName, 0666)
 	if err != nil {
 		return
 	}
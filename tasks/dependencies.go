@@ -0,0 +1,188 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"fmt"
+	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/openxo/goxc/config"
+	"github.com/openxo/goxc/core"
+	"github.com/openxo/goxc/typeutils"
+	"log"
+	"strings"
+)
+
+// resolve aliases into tasks
+// 0.11.x aliases are resolved recursively (aliases can contain aliases). A cycle is logged, and its aliases are left unresolved.
+// Use ResolveNestedAliases to get the error instead.
+func ResolveAliases(tasks []string) []string {
+	resolved, err := ResolveNestedAliases(tasks)
+	if err != nil {
+		log.Printf("%v", err)
+		return tasks
+	}
+	return resolved
+}
+
+// 0.11.x resolve aliases into tasks, recursively (aliases can contain aliases). Cycles are reported as an error.
+func ResolveNestedAliases(tasks []string) ([]string, error) {
+	return resolveAliases(tasks, Aliases, []string{})
+}
+
+// 0.11.x ordering-only dependencies: when a task runs along with any of these tasks (or aliases), it runs after them.
+// Unlike Dependencies, they aren't added to the tasks being run.
+var runsAfter = map[string][]string{
+	//binaries are needed until they're signed, archived & packaged
+	TASK_REMOVE_BIN: {TASK_CODESIGN, TASK_EXEC, TASKALIAS_ARCHIVE, TASK_PKG_BUILD},
+}
+
+// 0.11.x built-in aliases plus any user-defined aliases from config.
+// User-defined aliases can override built-in aliases, but not tasks.
+func AllAliases(settings config.Settings) map[string][]string {
+	aliases := map[string][]string{}
+	for alias, taskNames := range Aliases {
+		aliases[alias] = taskNames
+	}
+	for alias, taskNames := range settings.Aliases {
+		if _, keyExists := allTasks[alias]; keyExists {
+			core.Warnf("alias '%s' has the same name as a task. Ignoring alias", alias)
+			continue
+		}
+		if _, keyExists := Aliases[alias]; keyExists && settings.IsVerbose() {
+			log.Printf("Alias '%s' overrides the built-in alias", alias)
+		}
+		aliases[alias] = taskNames
+	}
+	return aliases
+}
+
+// resolve aliases (built-in and user-defined) into tasks
+func ResolveAllAliases(tasks []string, settings config.Settings) ([]string, error) {
+	return resolveAliases(tasks, AllAliases(settings), []string{})
+}
+
+func resolveAliases(tasks []string, aliases map[string][]string, path []string) ([]string, error) {
+	ret := []string{}
+	for _, taskName := range tasks {
+		if aliasTasks, keyExists := aliases[taskName]; keyExists {
+			if typeutils.StringSliceContains(path, taskName) {
+				return nil, fmt.Errorf("alias cycle detected: %s -> %s", strings.Join(path, " -> "), taskName)
+			}
+			thisPath := append(append([]string{}, path...), taskName)
+			resolved, err := resolveAliases(aliasTasks, aliases, thisPath)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, resolved...)
+		} else {
+			ret = append(ret, taskName)
+		}
+	}
+	return ret, nil
+}
+
+// resolve a task's dependencies into task names, given the registered tasks (normally allTasks)
+func getDependencies(registry map[string]Task, taskName string) ([]string, error) {
+	task, keyExists := registry[taskName]
+	if !keyExists {
+		//unknown tasks are reported later
+		return []string{}, nil
+	}
+	return ResolveNestedAliases(task.Dependencies)
+}
+
+// Builds the dependency graph for the given tasks, and returns tasks in the order they should be run.
+// Dependencies come before the tasks which depend on them, as do any tasks which they run after (see runsAfter); otherwise the given order is kept.
+// Each task appears once. Excluded tasks are left out, with a warning if another task depends on them.
+func orderTasks(registry map[string]Task, tasks []string, exclusions []string) ([]string, error) {
+	//the tasks being run, including dependencies
+	running := append([]string{}, tasks...)
+	for _, taskName := range tasks {
+		running = append(running, transitiveDependencies(registry, taskName)...)
+	}
+	ordered := []string{}
+	//tasks currently being visited (for cycle detection)
+	visiting := []string{}
+	var visit func(taskName string) error
+	visit = func(taskName string) error {
+		if typeutils.StringSliceContains(ordered, taskName) {
+			return nil
+		}
+		if typeutils.StringSliceContains(visiting, taskName) {
+			return fmt.Errorf("task dependency cycle detected: %s -> %s", strings.Join(visiting, " -> "), taskName)
+		}
+		visiting = append(visiting, taskName)
+		deps, err := getDependencies(registry, taskName)
+		if err != nil {
+			return err
+		}
+		for _, dep := range deps {
+			if typeutils.StringSliceContains(exclusions, dep) {
+				core.Warnf("task '%s' depends on '%s', which is excluded", taskName, dep)
+				continue
+			}
+			err = visit(dep)
+			if err != nil {
+				return err
+			}
+		}
+		previous, err := ResolveNestedAliases(runsAfter[taskName])
+		if err != nil {
+			return err
+		}
+		for _, prev := range previous {
+			if typeutils.StringSliceContains(running, prev) && !typeutils.StringSliceContains(exclusions, prev) {
+				err = visit(prev)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		visiting = visiting[:len(visiting)-1]
+		ordered = append(ordered, taskName)
+		return nil
+	}
+	for _, taskName := range tasks {
+		if typeutils.StringSliceContains(exclusions, taskName) {
+			continue
+		}
+		err := visit(taskName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// All (direct or indirect) dependencies of the given task.
+func transitiveDependencies(registry map[string]Task, taskName string) []string {
+	return appendDependencies(registry, taskName, []string{})
+}
+
+func appendDependencies(registry map[string]Task, taskName string, found []string) []string {
+	deps, err := getDependencies(registry, taskName)
+	if err != nil {
+		return found
+	}
+	for _, dep := range deps {
+		if !typeutils.StringSliceContains(found, dep) {
+			found = appendDependencies(registry, dep, append(found, dep))
+		}
+	}
+	return found
+}
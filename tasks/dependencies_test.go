@@ -0,0 +1,87 @@
+package tasks
+
+import (
+	"github.com/openxo/goxc/typeutils"
+	"reflect"
+	"testing"
+)
+
+func TestResolveAliasesNested(t *testing.T) {
+	aliases := map[string][]string{
+		"outer": []string{"a", "inner", "d"},
+		"inner": []string{"b", "c"},
+	}
+	resolved, err := resolveAliases([]string{"outer"}, aliases, []string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"a", "b", "c", "d"}
+	if !reflect.DeepEqual(resolved, expected) {
+		t.Fatalf("unexpected result %v, expected %v", resolved, expected)
+	}
+}
+
+func TestResolveAliasesCycle(t *testing.T) {
+	aliases := map[string][]string{
+		"x": []string{"a", "y"},
+		"y": []string{"x"},
+	}
+	_, err := resolveAliases([]string{"x"}, aliases, []string{})
+	if err == nil {
+		t.Fatalf("expected a cycle error")
+	}
+}
+
+func TestOrderTasks(t *testing.T) {
+	//a local registry, so that these tasks aren't registered for other tests
+	registry := map[string]Task{
+		"dep-test-a": Task{"dep-test-a", "test", nil, nil, nil},
+		"dep-test-b": Task{"dep-test-b", "test", nil, nil, []string{"dep-test-a"}},
+		"dep-test-c": Task{"dep-test-c", "test", nil, nil, []string{"dep-test-b", "dep-test-a"}},
+	}
+	ordered, err := orderTasks(registry, []string{"dep-test-c", "dep-test-a"}, []string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"dep-test-a", "dep-test-b", "dep-test-c"}
+	if !reflect.DeepEqual(ordered, expected) {
+		t.Fatalf("unexpected result %v, expected %v", ordered, expected)
+	}
+	ordered, err = orderTasks(registry, []string{"dep-test-c"}, []string{"dep-test-b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = []string{"dep-test-a", "dep-test-c"}
+	if !reflect.DeepEqual(ordered, expected) {
+		t.Fatalf("unexpected result %v, expected %v", ordered, expected)
+	}
+}
+
+func TestOrderTasksCycle(t *testing.T) {
+	registry := map[string]Task{
+		"cycle-test-a": Task{"cycle-test-a", "test", nil, nil, []string{"cycle-test-b"}},
+		"cycle-test-b": Task{"cycle-test-b", "test", nil, nil, []string{"cycle-test-a"}},
+	}
+	_, err := orderTasks(registry, []string{"cycle-test-a"}, []string{})
+	if err == nil {
+		t.Fatalf("expected a cycle error")
+	}
+}
+
+func TestOrderTasksRunsAfter(t *testing.T) {
+	ordered, err := orderTasks(allTasks, []string{TASK_REMOVE_BIN, TASK_ARCHIVE_ZIP}, []string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ordered[len(ordered)-1] != TASK_REMOVE_BIN {
+		t.Fatalf("expected %s to run last, got %v", TASK_REMOVE_BIN, ordered)
+	}
+	//not added when it isn't being run anyway
+	ordered, err = orderTasks(allTasks, []string{TASK_REMOVE_BIN}, []string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if typeutils.StringSliceContains(ordered, TASK_ARCHIVE_ZIP) {
+		t.Fatalf("unexpected result %v", ordered)
+	}
+}
@@ -17,8 +17,13 @@ package tasks
 */
 
 import (
+	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/openxo/goxc/core"
+	"github.com/openxo/goxc/platforms"
 	htemplate "html/template"
-	"os"
+	"io"
+	"path"
 	"path/filepath"
 	"strings"
 	"text/template"
@@ -48,7 +53,8 @@ title: Downloads
 
 {{.ExtraVars.footer}}`,
 			"templateFile":      "",
-			"templateExtraVars": map[string]interface{}{"footer": "Generated by goxc"}}})
+			"templateExtraVars": map[string]interface{}{"footer": "Generated by goxc"}},
+		nil})
 
 }
 
@@ -80,27 +86,29 @@ func runTaskDownloadsPage(tp TaskParams) error {
 	}
 	templateVars := tp.Settings.GetTaskSettingMap(TASK_DOWNLOADS_PAGE, "templateExtraVars")
 	reportFilename := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName(), outFilename)
-	flags := os.O_WRONLY | os.O_TRUNC | os.O_CREATE
-	out, err := os.OpenFile(reportFilename, flags, 0600)
+	out, err := core.CreateFile(reportFilename, 0600)
 	if err != nil {
 		return err
 	}
 	defer out.Close()
 	report := Report{tp.AppName, tp.Settings.GetFullVersionName(), map[string]*[]Download{}, templateVars}
-	versionDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
-	err = filepath.Walk(versionDir, func(path string, info os.FileInfo, e error) error {
-		return downloadsWalkFunc(path, info, e, tp, report, outFilename, format)
-	})
+	//0.11.x artifacts come from the manifest, rather than walking the version dir
+	for _, artifact := range tp.artifacts.list() {
+		if artifact.Kind != ARTIFACT_PAGE {
+			addDownload(artifact, report, format)
+		}
+	}
+	err = runTemplate(reportFilename, templateFile, templateText, out, report, format)
 	if err != nil {
 		return err
 	}
-	err = runTemplate(reportFilename, templateFile, templateText, out, report, format)
+	err = out.Close()
 	if err != nil {
 		return err
 	}
-	return out.Close()
+	return tp.artifacts.register(ARTIFACT_PAGE, TASK_DOWNLOADS_PAGE, platforms.Platform{}, "", reportFilename)
 }
-func runTemplate(reportFilename, templateFile, templateText string, out *os.File, data interface{}, format string) (err error) {
+func runTemplate(reportFilename, templateFile, templateText string, out io.Writer, data interface{}, format string) (err error) {
 	var tmpl *template.Template
 	var htmpl *htemplate.Template
 	if templateFile != "" {
@@ -131,42 +139,38 @@ func runTemplate(reportFilename, templateFile, templateText string, out *os.File
 	return err
 }
 
-func getCategory(relativePath string) string {
-	category := "Other files"
-	if strings.Contains(relativePath, "linux") || strings.HasSuffix(relativePath, ".deb") {
-		category = "Linux"
-	} else if strings.Contains(relativePath, "darwin") {
-		category = "Darwin (Apple Mac)"
-	} else if strings.Contains(relativePath, "netbsd") {
-		category = "NetBSD"
-	} else if strings.Contains(relativePath, "freebsd") {
-		category = "FreeBSD"
-	} else if strings.Contains(relativePath, "windows") {
-		category = "MS Windows"
-	} else if strings.Contains(relativePath, "openbsd") {
-		category = "OpenBSD"
-	} else if strings.Contains(relativePath, "plan9") {
-		category = "Plan 9"
+// 0.11.x categorised by the artifact's OS
+func getCategory(artifact Artifact) string {
+	switch artifact.Os {
+	case "":
+		return "Other files"
+	case platforms.LINUX:
+		return "Linux"
+	case platforms.DARWIN:
+		return "Darwin (Apple Mac)"
+	case platforms.NETBSD:
+		return "NetBSD"
+	case platforms.FREEBSD:
+		return "FreeBSD"
+	case platforms.WINDOWS:
+		return "MS Windows"
+	case platforms.OPENBSD:
+		return "OpenBSD"
+	case platforms.PLAN9:
+		return "Plan 9"
 	}
-	return category
+	return artifact.Os
 }
 
-func downloadsWalkFunc(fullPath string, fi2 os.FileInfo, err error, tp TaskParams, report Report, reportFilename, format string) error {
-	if fi2.IsDir() || fi2.Name() == reportFilename {
-		return nil
-	}
-
-	versionDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
-	relativePath := strings.Replace(fullPath, versionDir, "", -1)
-	relativePath = strings.TrimPrefix(relativePath, "/")
-	text := fi2.Name()
+func addDownload(artifact Artifact, report Report, format string) {
+	text := path.Base(artifact.Path)
 	if format == "markdown" {
 		text = strings.Replace(text, "_", "\\_", -1)
 	}
-	category := getCategory(relativePath)
+	category := getCategory(artifact)
 
-	//log.Printf("Adding: %s", relativePath)
-	download := Download{text, relativePath}
+	//log.Printf("Adding: %s", artifact.Path)
+	download := Download{text, artifact.Path}
 	v, ok := report.Categories[category]
 	var existing []Download
 	if !ok {
@@ -177,6 +181,4 @@ func downloadsWalkFunc(fullPath string, fi2 os.FileInfo, err error, tp TaskParam
 
 	existing = append(existing, download)
 	report.Categories[category] = &existing
-	//entryCount++
-	return nil
 }
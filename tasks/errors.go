@@ -0,0 +1,84 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// A failure of one task, for one platform and one main dir (where relevant).
+type TaskError struct {
+	Task     string
+	Platform string
+	MainDir  string
+	Err      error
+}
+
+func (e TaskError) Error() string {
+	desc := e.Task
+	if e.Platform != "" {
+		desc += " (" + e.Platform + ")"
+	}
+	if e.MainDir != "" {
+		desc += " [" + e.MainDir + "]"
+	}
+	return fmt.Sprintf("%s: %v", desc, e.Err)
+}
+
+// All the failures during a run.
+type TaskErrors []TaskError
+
+func (errs TaskErrors) Error() string {
+	msgs := []string{}
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	return fmt.Sprintf("%d failure(s): %s", len(errs), strings.Join(msgs, "; "))
+}
+
+// flatten any error into TaskErrors, attributing plain errors to the given task
+func toTaskErrors(taskName string, err error) TaskErrors {
+	switch typedErr := err.(type) {
+	case TaskErrors:
+		return typedErr
+	case TaskError:
+		return TaskErrors{typedErr}
+	}
+	return TaskErrors{TaskError{Task: taskName, Err: err}}
+}
+
+// Writes a table of failures
+func (errs TaskErrors) PrintSummary(w io.Writer) {
+	fmt.Fprintf(w, "Failure summary (%d):\n", len(errs))
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, " TASK\tPLATFORM\tMAIN DIR\tERROR")
+	for _, e := range errs {
+		platform := e.Platform
+		if platform == "" {
+			platform = "-"
+		}
+		mainDir := e.MainDir
+		if mainDir == "" {
+			mainDir = "-"
+		}
+		fmt.Fprintf(tw, " %s\t%s\t%s\t%v\n", e.Task, platform, mainDir, e.Err)
+	}
+	tw.Flush()
+}
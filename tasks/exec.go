@@ -0,0 +1,273 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/openxo/goxc/executils"
+	"github.com/openxo/goxc/platforms"
+	"github.com/openxo/goxc/typeutils"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"text/template"
+)
+
+const (
+	TASK_EXEC = "exec"
+
+	EXEC_SCOPE_ONCE     = "once"
+	EXEC_SCOPE_PLATFORM = "platform"
+	EXEC_SCOPE_BINARY   = "binary"
+)
+
+//runs automatically
+func init() {
+	Register(Task{
+		TASK_EXEC,
+		"Run arbitrary commands (e.g. `go generate`, upx, a signing tool). Each command is a template, run once, per platform or per binary. See `goxc -h exec`",
+		runTaskExec,
+		map[string]interface{}{
+			//each command is either a string (run once), or a map with keys 'command', 'scope' (once/platform/binary), and optionally 'platforms' (build constraints), 'dir' & 'env'.
+			//commands are text/templates, with fields .Os .Arch .Variant .AppName .Version .BinPath .OutDir .MainDir .WorkingDirectory
+			"commands": []interface{}{}},
+		nil})
+}
+
+// a command, as configured
+type execCommand struct {
+	Command   string
+	Scope     string
+	Platforms string
+	Dir       string
+	Env       []string
+}
+
+// the data available to command templates
+type execTemplateData struct {
+	Os               string
+	Arch             string
+	Variant          string
+	AppName          string
+	Version          string
+	BinPath          string
+	OutDir           string
+	MainDir          string
+	WorkingDirectory string
+}
+
+func runTaskExec(tp TaskParams) error {
+	commands, err := getExecCommands(tp.Settings.GetTaskSetting(TASK_EXEC, "commands"))
+	if err != nil {
+		return err
+	}
+	if len(commands) == 0 {
+		log.Printf("No commands configured for task '%s'", TASK_EXEC)
+	}
+	for _, command := range commands {
+		err = runExecCommand(command, tp)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runExecCommand(command execCommand, tp TaskParams) error {
+	tmpl, err := template.New(TASK_EXEC).Parse(command.Command)
+	if err != nil {
+		return err
+	}
+	data := execTemplateData{
+		AppName:          tp.AppName,
+		Version:          tp.Settings.GetFullVersionName(),
+		OutDir:           filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName()),
+		WorkingDirectory: tp.WorkingDirectory,
+	}
+	if command.Scope == EXEC_SCOPE_ONCE {
+		return execTemplate(tmpl, data, command, tp, executils.StdLogger())
+	}
+	destPlatforms := tp.DestPlatforms
+	if command.Platforms != "" {
+		destPlatforms = []platforms.Platform{}
+		for _, dest := range tp.DestPlatforms {
+			if platforms.MatchesBuildConstraints(command.Platforms, dest) {
+				destPlatforms = append(destPlatforms, dest)
+			}
+		}
+	}
+	var jobs []platformJob
+	if command.Scope == EXEC_SCOPE_BINARY {
+		jobs = tp.binaryJobs(destPlatforms)
+	} else {
+		jobs = jobsPerPlatform(destPlatforms)
+	}
+	//0.11.x platforms are processed concurrently
+	return runPlatformJobs(tp, TASK_EXEC, jobs, func(job platformJob, logger *log.Logger) error {
+		jobData := data
+		jobData.Os = job.Platform.Os
+		jobData.Arch = job.Platform.Arch
+		jobData.Variant = job.Platform.Variant
+		jobData.MainDir = job.MainDir
+		if job.MainDir == "" && len(tp.MainDirs) == 1 {
+			//only one binary per platform anyway
+			jobData.MainDir = tp.MainDirs[0]
+		}
+		if jobData.MainDir != "" {
+			jobData.BinPath = tp.binaryPath(job.Platform, jobData.MainDir)
+		}
+		//0.11.x Env from any PlatformOverrides
+		return execTemplate(tmpl, jobData, command, tp.forPlatform(job.Platform), logger)
+	})
+}
+
+func execTemplate(tmpl *template.Template, data execTemplateData, command execCommand, tp TaskParams, logger *log.Logger) error {
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, data)
+	if err != nil {
+		return err
+	}
+	args, err := splitCommandLine(buf.String())
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("empty command: '%s'", command.Command)
+	}
+	env := append(append([]string{}, tp.Settings.Env...), command.Env...)
+	if data.Os != "" {
+		env = append(env, "GOOS="+data.Os, "GOARCH="+data.Arch)
+		env = append(env, platforms.Platform{Os: data.Os, Arch: data.Arch, Variant: data.Variant}.VariantEnv()...)
+	}
+	dir := tp.WorkingDirectory
+	if command.Dir != "" {
+		dir = command.Dir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(tp.WorkingDirectory, dir)
+		}
+	}
+	cmd := exec.Command(args[0])
+	err = executils.PrepareCmd(cmd, dir, args[1:], env, tp.Settings.IsVerbose())
+	if err != nil {
+		return err
+	}
+	stdout := executils.NewLogWriter(logger)
+	stderr := executils.NewLogWriter(logger)
+	executils.RedirectIOTo(cmd, os.Stdin, stdout, stderr)
+	defer stdout.Flush()
+	defer stderr.Flush()
+	logger.Printf("Running '%s'", executils.PrintableArgs(cmd.Args))
+	return executils.RunCmd(cmd, env)
+}
+
+// commands can be given as a string (for a single command), or a list of strings and/or maps
+func getExecCommands(v interface{}) ([]execCommand, error) {
+	commands := []execCommand{}
+	if v == nil {
+		return commands, nil
+	}
+	if commandString, ok := v.(string); ok {
+		return append(commands, execCommand{Command: commandString, Scope: EXEC_SCOPE_ONCE}), nil
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("exec.commands should be a json array (or string), not a %T", v)
+	}
+	for i, item := range items {
+		k := fmt.Sprintf("exec.commands[%d]", i)
+		if commandString, ok := item.(string); ok {
+			commands = append(commands, execCommand{Command: commandString, Scope: EXEC_SCOPE_ONCE})
+			continue
+		}
+		m, err := typeutils.ToMap(item, k)
+		if err != nil {
+			return nil, err
+		}
+		command := execCommand{Scope: EXEC_SCOPE_ONCE}
+		for k2, v2 := range m {
+			switch k2 {
+			case "command":
+				command.Command, err = typeutils.ToString(v2, k+"."+k2)
+			case "scope":
+				command.Scope, err = typeutils.ToString(v2, k+"."+k2)
+			case "platforms":
+				command.Platforms, err = typeutils.ToString(v2, k+"."+k2)
+			case "dir":
+				command.Dir, err = typeutils.ToString(v2, k+"."+k2)
+			case "env":
+				command.Env, err = typeutils.ToStringSlice(v2, k+"."+k2)
+			default:
+				err = fmt.Errorf("%s: unrecognised key '%s'", k, k2)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		switch command.Scope {
+		case EXEC_SCOPE_ONCE, EXEC_SCOPE_PLATFORM, EXEC_SCOPE_BINARY:
+		default:
+			return nil, fmt.Errorf("%s: invalid scope '%s' (use %s, %s or %s)", k, command.Scope, EXEC_SCOPE_ONCE, EXEC_SCOPE_PLATFORM, EXEC_SCOPE_BINARY)
+		}
+		if command.Command == "" {
+			return nil, fmt.Errorf("%s: 'command' is missing", k)
+		}
+		commands = append(commands, command)
+	}
+	return commands, nil
+}
+
+// Splits a command line into args, respecting single & double quotes (no other shell features).
+func splitCommandLine(line string) ([]string, error) {
+	args := []string{}
+	var current bytes.Buffer
+	inArg := false
+	var quote rune
+	for _, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				current.WriteRune(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(c)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote in command: " + line)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
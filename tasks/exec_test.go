@@ -0,0 +1,41 @@
+package tasks
+
+import (
+	"testing"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	args, err := splitCommandLine(`upx --best "my app/bin" 'a b'`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	expected := []string{"upx", "--best", "my app/bin", "a b"}
+	if len(args) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, args)
+	}
+	for i := range expected {
+		if args[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, args)
+		}
+	}
+	_, err = splitCommandLine(`echo "oops`)
+	if err == nil {
+		t.Errorf("Expected an error for an unterminated quote")
+	}
+}
+
+func TestGetExecCommands(t *testing.T) {
+	commands, err := getExecCommands([]interface{}{
+		"go generate ./...",
+		map[string]interface{}{"command": "upx {{.BinPath}}", "scope": "binary", "platforms": "linux"}})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(commands) != 2 || commands[0].Scope != EXEC_SCOPE_ONCE || commands[1].Scope != EXEC_SCOPE_BINARY || commands[1].Platforms != "linux" {
+		t.Errorf("Unexpected commands %+v", commands)
+	}
+	_, err = getExecCommands([]interface{}{map[string]interface{}{"command": "x", "scope": "sometimes"}})
+	if err == nil {
+		t.Errorf("Expected an error for an invalid scope")
+	}
+}
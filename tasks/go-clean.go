@@ -28,6 +28,7 @@ func init() {
 		TASK_GO_CLEAN,
 		"runs `go clean`.",
 		runTaskGoClean,
+		nil,
 		nil})
 }
 
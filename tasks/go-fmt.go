@@ -28,7 +28,8 @@ func init() {
 		TASK_GO_FMT,
 		"runs `go fmt ./...`.",
 		runTaskGoFmt,
-		map[string]interface{}{"dir": "./..."}})
+		map[string]interface{}{"dir": "./..."},
+		nil})
 }
 
 func runTaskGoFmt(tp TaskParams) error {
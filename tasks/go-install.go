@@ -28,12 +28,14 @@ func init() {
 		TASK_GO_INSTALL,
 		"runs `go install`. installs a version consistent with goxc-built binaries.",
 		runTaskGoInstall,
+		nil,
 		nil})
 }
 
 func runTaskGoInstall(tp TaskParams) error {
 	for _, mainDir := range tp.MainDirs {
-		err := executils.InvokeGo(mainDir, "install", []string{}, []string{}, tp.Settings)
+		//0.11.x with the binary's own BuildSettings
+		err := executils.InvokeGo(mainDir, "install", []string{}, []string{}, tp.Settings.ForBinary(tp.relativeMainDir(mainDir)))
 		if err != nil {
 			return err
 		}
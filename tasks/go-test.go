@@ -28,7 +28,8 @@ func init() {
 		TASK_GO_TEST,
 		"runs `go test ./...`. (dir is configurable).",
 		runTaskGoTest,
-		map[string]interface{}{"dir": "./..."}})
+		map[string]interface{}{"dir": "./..."},
+		nil})
 }
 
 func runTaskGoTest(tp TaskParams) error {
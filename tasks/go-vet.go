@@ -29,7 +29,8 @@ func init() {
 		TASK_GO_VET,
 		"runs `go vet ./...`.",
 		runTaskGoVet,
-		map[string]interface{}{"dir": "./..."}})
+		map[string]interface{}{"dir": "./..."},
+		nil})
 }
 
 func runTaskGoVet(tp TaskParams) error {
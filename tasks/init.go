@@ -0,0 +1,349 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/openxo/goxc/config"
+	"github.com/openxo/goxc/core"
+	"github.com/openxo/goxc/source"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+const (
+	TASK_INIT = "init"
+
+	INIT_PLATFORMS_DEFAULT = "linux windows darwin"
+	INIT_VERSION_DEFAULT   = "0.0.1"
+)
+
+// Packaging formats offered by 'init', and the tasks which produce them
+var INIT_PACKAGING_FORMATS = map[string]string{
+	"zip":    TASK_ARCHIVE_ZIP,
+	"tar.gz": TASK_ARCHIVE_TAR_GZ,
+	"deb":    TASK_PKG_BUILD,
+}
+
+// Resources which are worth including in archives & packages
+var INIT_RESOURCES = []string{"README*", "LICENSE*", "LICENCE*", "COPYING*", "INSTALL*", "CHANGELOG*", "NOTICE*"}
+
+//runs automatically
+func init() {
+	Register(Task{
+		TASK_INIT,
+		"Create a commented config file (.goxc.yaml) for the working directory. Main packages, resources, the version and the maintainer are inferred from the source & git, and you're asked to confirm them, along with target platforms & packaging formats. Secrets (a bintray API key) go in .goxc.local.json. Use 'goxc -y init' to accept the defaults without prompting.",
+		runTaskInit,
+		nil,
+		nil})
+}
+
+func runTaskInit(tp TaskParams) error {
+	return InitConfig(tp.WorkingDirectory, core.GOXC_CONFIGNAME_BASE, false, os.Stdin, core.OutputWriter())
+}
+
+// Answers to the questions asked by 'init'
+type initAnswers struct {
+	AppName          string
+	MainDirs         []string
+	Version          string
+	BuildConstraints string
+	ResourcesInclude string
+	Formats          []string
+	Description      string
+	Maintainer       string
+	Homepage         string
+	BintraySubject   string
+	BintrayRepo      string
+	BintrayPackage   string
+	BintrayApiKey    string
+}
+
+// Asks questions, offering defaults. With 'isAcceptDefaults' (or at the end of input), defaults are used without asking.
+type prompter struct {
+	in               *bufio.Reader
+	out              io.Writer
+	isAcceptDefaults bool
+}
+
+func (p *prompter) ask(question, defaultValue string) string {
+	if p.isAcceptDefaults {
+		return defaultValue
+	}
+	if defaultValue != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, defaultValue)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	line, err := p.in.ReadString('\n')
+	if err != nil {
+		//end of input. Accept defaults from now on
+		fmt.Fprintln(p.out)
+		p.isAcceptDefaults = true
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return defaultValue
+	}
+	return line
+}
+
+// Creates a commented config file (<configName>.goxc.yaml), plus .goxc.local.json for any secrets.
+// Refuses to overwrite an existing config file.
+func InitConfig(workingDirectory, configName string, isAcceptDefaults bool, in io.Reader, out io.Writer) error {
+	base := configName + strings.TrimSuffix(core.GOXC_FILE_EXT, filepath.Ext(core.GOXC_FILE_EXT))
+	for _, ext := range config.CONFIG_FILE_EXTS {
+		existing := filepath.Join(workingDirectory, base+ext)
+		if _, err := os.Stat(existing); err == nil {
+			return fmt.Errorf("%s already exists. Use 'goxc -wc' to update it", existing)
+		}
+	}
+	answers, err := askInitQuestions(workingDirectory, &prompter{bufio.NewReader(in), out, isAcceptDefaults})
+	if err != nil {
+		return err
+	}
+	content, err := initConfigContent(answers)
+	if err != nil {
+		return err
+	}
+	configFile := filepath.Join(workingDirectory, base+".yaml")
+	err = config.ValidateConfig(configFile, content)
+	if err != nil {
+		return err
+	}
+	log.Printf("Writing file %s", configFile)
+	err = core.WriteFile(configFile, content, 0644)
+	if err != nil {
+		return err
+	}
+	if answers.BintrayApiKey != "" {
+		local := config.Settings{TaskSettings: map[string]map[string]interface{}{
+			TASK_BINTRAY: map[string]interface{}{"apikey": answers.BintrayApiKey}}}
+		err = config.WriteJsonConfig(workingDirectory, local, configName, true)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Keep %s out of version control (e.g. add it to .gitignore)\n", configName+core.GOXC_LOCAL_FILE_EXT)
+	}
+	return nil
+}
+
+func askInitQuestions(workingDirectory string, p *prompter) (initAnswers, error) {
+	answers := initAnswers{AppName: core.GetAppName(workingDirectory)}
+	mainDirs, err := source.FindMainDirs(workingDirectory, []string{})
+	if err != nil {
+		return answers, err
+	}
+	for _, mainDir := range mainDirs {
+		relative, err := filepath.Rel(workingDirectory, mainDir)
+		if err != nil {
+			relative = mainDir
+		}
+		answers.MainDirs = append(answers.MainDirs, relative)
+	}
+	if len(answers.MainDirs) == 0 {
+		core.Warnf("No main packages found in %s", workingDirectory)
+	} else {
+		fmt.Fprintf(p.out, "Main packages: %s\n", strings.Join(answers.MainDirs, ", "))
+	}
+
+	answers.Version = p.ask("Version", initVersion(workingDirectory))
+	answers.BuildConstraints = p.ask("Target platforms, as build constraints (e.g. 'linux,amd64 windows')", INIT_PLATFORMS_DEFAULT)
+	answers.ResourcesInclude = p.ask("Files to include in archives & packages", strings.Join(initResources(workingDirectory), ","))
+	for {
+		formats := strings.FieldsFunc(p.ask("Packaging formats (zip, tar.gz, deb)", "zip tar.gz deb"), func(r rune) bool { return r == ' ' || r == ',' })
+		unknown := []string{}
+		for _, format := range formats {
+			if _, keyExists := INIT_PACKAGING_FORMATS[format]; !keyExists {
+				unknown = append(unknown, format)
+			}
+		}
+		if len(unknown) == 0 {
+			answers.Formats = formats
+			break
+		}
+		fmt.Fprintf(p.out, "Unknown format(s): %s\n", strings.Join(unknown, ", "))
+		if p.isAcceptDefaults {
+			return answers, fmt.Errorf("unknown packaging format(s): %s", strings.Join(unknown, ", "))
+		}
+	}
+	repo, homepage := parseGitRemote(gitOutput(workingDirectory, "config", "--get", "remote.origin.url"))
+	if repo == "" {
+		repo = answers.AppName
+	}
+	if core.ContainsString(answers.Formats, "deb") {
+		answers.Description = p.ask("Description (for packages)", "")
+		answers.Maintainer = p.ask("Maintainer", initMaintainer(workingDirectory))
+		answers.Homepage = p.ask("Homepage", homepage)
+	}
+	answers.BintraySubject = p.ask("Bintray subject (user or organisation), to upload with the 'bintray' task. Leave blank to skip", "")
+	if answers.BintraySubject != "" {
+		answers.BintrayRepo = p.ask("Bintray repository", "generic")
+		answers.BintrayPackage = p.ask("Bintray package", repo)
+		answers.BintrayApiKey = p.ask("Bintray API key (written to "+core.GOXC_LOCAL_FILE_EXT+"). Leave blank to skip", "")
+	}
+	return answers, nil
+}
+
+// The latest tag, without the tag task's 'v' prefix
+func initVersion(workingDirectory string) string {
+	tag := gitOutput(workingDirectory, "describe", "--tags", "--abbrev=0")
+	if tag == "" {
+		return INIT_VERSION_DEFAULT
+	}
+	return strings.TrimPrefix(tag, "v")
+}
+
+func initResources(workingDirectory string) []string {
+	found := []string{}
+	for _, pattern := range INIT_RESOURCES {
+		matches, _ := filepath.Glob(filepath.Join(workingDirectory, pattern))
+		for _, match := range matches {
+			name := filepath.Base(match)
+			if !core.ContainsString(found, name) {
+				found = append(found, name)
+			}
+		}
+	}
+	return found
+}
+
+func initMaintainer(workingDirectory string) string {
+	name := gitOutput(workingDirectory, "config", "--get", "user.name")
+	email := gitOutput(workingDirectory, "config", "--get", "user.email")
+	if email == "" {
+		return name
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s <%s>", name, email))
+}
+
+func gitOutput(workingDirectory string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = workingDirectory
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+var gitRemotePattern = regexp.MustCompile(`^(?:[a-z+]+://)?(?:[^@/]+@)?([^/:]+)[:/](?:\d+/)?([^/]+)/(.+?)(?:\.git)?/?$`)
+
+// repository name & web page for a remote such as 'git@github.com:owner/repo.git' or 'https://github.com/owner/repo'
+func parseGitRemote(remote string) (string, string) {
+	matches := gitRemotePattern.FindStringSubmatch(remote)
+	if matches == nil {
+		return "", ""
+	}
+	host, owner, repo := matches[1], matches[2], matches[3]
+	return repo, "https://" + host + "/" + owner + "/" + repo
+}
+
+const initConfigTemplate = `# goxc config for {{.AppName}}, created by 'goxc init'.
+# See 'goxc -h options' for settings, and 'goxc -h <task>' for task settings.
+ConfigVersion: {{q .ConfigVersion}}
+
+# Used in artifact names. 'goxc bump' increases it.
+PackageVersion: {{q .Version}}
+
+# Platforms to build for. Space-separated constraints are OR'd, comma-separated ones are AND'd (e.g. "linux,arm64 windows,amd64").
+BuildConstraints: {{q .BuildConstraints}}
+{{if .MainDirs}}
+# Main packages (all of them are built): {{join .MainDirs ", "}}
+# To skip some, set e.g. MainDirsExclude: "examples,testdata"
+{{end}}{{if .ResourcesInclude}}
+# Files to include in archives & packages
+ResourcesInclude: {{q .ResourcesInclude}}
+{{end}}{{if .TasksExclude}}
+# Packaging formats which aren't wanted: {{join .FormatsExcluded ", "}}
+TasksExclude:
+{{range .TasksExclude}}  - {{q .}}
+{{end}}{{end}}{{if .TaskSettings}}
+TaskSettings:
+{{.TaskSettings}}{{end}}`
+
+func initConfigContent(answers initAnswers) ([]byte, error) {
+	data := struct {
+		initAnswers
+		ConfigVersion   string
+		TasksExclude    []string
+		FormatsExcluded []string
+		TaskSettings    string
+	}{initAnswers: answers, ConfigVersion: config.GOXC_CONFIG_VERSION}
+	for _, format := range []string{"zip", "tar.gz", "deb"} {
+		if !core.ContainsString(answers.Formats, format) {
+			data.FormatsExcluded = append(data.FormatsExcluded, format)
+			data.TasksExclude = append(data.TasksExclude, INIT_PACKAGING_FORMATS[format])
+		}
+	}
+	//zip & tar.gz are divided between platforms by default. If only one is wanted, use it for all platforms
+	taskSettings := &bytes.Buffer{}
+	isZip, isTarGz := core.ContainsString(answers.Formats, "zip"), core.ContainsString(answers.Formats, "tar.gz")
+	if isZip != isTarGz {
+		archiveTask := TASK_ARCHIVE_ZIP
+		if isTarGz {
+			archiveTask = TASK_ARCHIVE_TAR_GZ
+		}
+		fmt.Fprintf(taskSettings, "  # The only archive format, so it's used for all platforms\n  %s:\n    platforms: %s\n", archiveTask, yamlQuote(answers.BuildConstraints))
+	}
+	if core.ContainsString(answers.Formats, "deb") {
+		if answers.Maintainer != "" {
+			fmt.Fprintf(taskSettings, "  %s:\n    metadata:\n      maintainer: %s\n", TASK_PKG_BUILD, yamlQuote(answers.Maintainer))
+		} else {
+			fmt.Fprintf(taskSettings, "  %s:\n    metadata:\n      # e.g. \"Your Name <you@example.com>\"\n      maintainer: \"unknown\"\n", TASK_PKG_BUILD)
+		}
+		if answers.Description != "" {
+			fmt.Fprintf(taskSettings, "      description: %s\n", yamlQuote(answers.Description))
+		}
+		if answers.Homepage != "" {
+			fmt.Fprintf(taskSettings, "    # Extra fields for the debian control file\n    metadata-deb:\n      Homepage: %s\n", yamlQuote(answers.Homepage))
+		}
+	}
+	if answers.BintraySubject != "" {
+		fmt.Fprintf(taskSettings, "  %s:\n    subject: %s\n    repository: %s\n    package: %s\n", TASK_BINTRAY, yamlQuote(answers.BintraySubject), yamlQuote(answers.BintrayRepo), yamlQuote(answers.BintrayPackage))
+		if answers.BintrayApiKey != "" {
+			fmt.Fprintf(taskSettings, "    # apikey is in %s\n", core.GOXC_LOCAL_FILE_EXT)
+		} else {
+			fmt.Fprintf(taskSettings, "    # Put the apikey in %s (or use \"${BINTRAY_APIKEY}\")\n", core.GOXC_LOCAL_FILE_EXT)
+		}
+	}
+	data.TaskSettings = taskSettings.String()
+	tmpl, err := template.New("config").Funcs(template.FuncMap{"q": yamlQuote, "join": strings.Join}).Parse(initConfigTemplate)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	err = tmpl.Execute(buf, data)
+	return buf.Bytes(), err
+}
+
+// json strings are valid (double-quoted) yaml strings
+func yamlQuote(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
@@ -0,0 +1,70 @@
+package tasks
+
+import (
+	"bytes"
+	"github.com/openxo/goxc/config"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-init")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "cmd", "app"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "cmd", "app", "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("readme"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "LICENSE"), []byte("license"), 0644)
+	//version, platforms, resources, formats (one unknown, then zip only), bintray subject, repo, package, apikey
+	in := strings.NewReader("1.2.0\nlinux,amd64 windows\n\nzip rpm\nzip\nme\n\n\nsecret-key\n")
+	out := &bytes.Buffer{}
+	err = InitConfig(dir, "", false, in, out)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "Main packages: "+filepath.Join("cmd", "app")) || !strings.Contains(out.String(), "Unknown format(s): rpm") {
+		t.Errorf("Unexpected output: %s", out.String())
+	}
+	content, _ := ioutil.ReadFile(filepath.Join(dir, ".goxc.yaml"))
+	if !strings.HasPrefix(string(content), "# goxc config") {
+		t.Errorf("Expected a commented config, got %s", content)
+	}
+	settings, err := config.LoadJsonConfigs(dir, []string{filepath.Join(dir, ".goxc.json"), filepath.Join(dir, ".goxc.local.json")}, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if settings.PackageVersion != "1.2.0" || settings.BuildConstraints != "linux,amd64 windows" || settings.ResourcesInclude != "README.md,LICENSE" {
+		t.Errorf("Unexpected settings: %+v\n%s", settings, content)
+	}
+	if strings.Join(settings.TasksExclude, ",") != "archive-tar-gz,pkg-build" || settings.GetTaskSettingString(TASK_ARCHIVE_ZIP, "platforms") != "linux,amd64 windows" {
+		t.Errorf("Unexpected packaging settings: %v, %+v\n%s", settings.TasksExclude, settings.TaskSettings, content)
+	}
+	if settings.GetTaskSettingString(TASK_BINTRAY, "subject") != "me" || settings.GetTaskSettingString(TASK_BINTRAY, "package") != filepath.Base(dir) || settings.GetTaskSettingString(TASK_BINTRAY, "apikey") != "secret-key" {
+		t.Errorf("Unexpected bintray settings: %+v", settings.TaskSettings[TASK_BINTRAY])
+	}
+	if strings.Contains(string(content), "secret-key") {
+		t.Errorf("The apikey should only be in .goxc.local.json")
+	}
+	//no overwriting
+	err = InitConfig(dir, "", true, strings.NewReader(""), out)
+	if err == nil {
+		t.Errorf("Expected an error for an existing config file")
+	}
+}
+
+func TestParseGitRemote(t *testing.T) {
+	for _, remote := range []string{"git@github.com:owner/repo.git", "https://github.com/owner/repo", "ssh://git@github.com/owner/repo.git"} {
+		repo, homepage := parseGitRemote(remote)
+		if repo != "repo" || homepage != "https://github.com/owner/repo" {
+			t.Errorf("%s: unexpected repo '%s', homepage '%s'", remote, repo, homepage)
+		}
+	}
+	if repo, _ := parseGitRemote(""); repo != "" {
+		t.Errorf("Expected no repo for no remote")
+	}
+}
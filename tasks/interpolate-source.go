@@ -20,12 +20,12 @@ import (
 	"fmt"
 	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
 	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/openxo/goxc/core"
 	"github.com/openxo/goxc/source"
 	"go/parser"
 	"go/printer"
 	"go/token"
 	"log"
-	"os"
 	"path/filepath"
 	"time"
 )
@@ -40,7 +40,8 @@ func init() {
 		TASK_INTERPOLATE_SOURCE,
 		"Replaces a given constant/var value with the current version.",
 		runTaskInterpolateSource,
-		map[string]interface{}{"varnameVersion": "VERSION", "varnameBuildDate": "BUILD_DATE"}})
+		map[string]interface{}{"varnameVersion": "VERSION", "varnameBuildDate": "BUILD_DATE"},
+		nil})
 }
 
 func runTaskInterpolateSource(tp TaskParams) error {
@@ -82,7 +83,7 @@ func writeVar(tp TaskParams, varname, varval string) (err error) {
 				varvalQuoted := fmt.Sprintf("\"%s\"", varval)
 				log.Printf("Changing source of '%s' = %v -> %s", varname, versionVar.Value, varvalQuoted)
 				versionVar.Value = varvalQuoted
-				fw, err := os.OpenFile(match, os.O_WRONLY|os.O_TRUNC, 0644)
+				fw, err := core.CreateFile(match, 0644)
 				if err != nil {
 					return err
 				}
@@ -0,0 +1,40 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/openxo/goxc/config"
+	"github.com/openxo/goxc/core"
+)
+
+const TASK_MIGRATE_CONFIG = "migrate-config"
+
+//runs automatically
+func init() {
+	Register(Task{
+		TASK_MIGRATE_CONFIG,
+		"Rewrite old config files (*.goxc.json and *.goxc.local.json, or their yaml or toml equivalents) in the working directory to the current ConfigVersion. Originals are kept as '.bak' files, and a diff is printed. (When run on its own, config files aren't loaded first, so this works even if they're no longer valid)",
+		runTaskMigrateConfig,
+		nil,
+		nil})
+}
+
+func runTaskMigrateConfig(tp TaskParams) error {
+	return config.MigrateConfigFiles(tp.WorkingDirectory, core.OutputWriter())
+}
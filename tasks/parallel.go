@@ -0,0 +1,158 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/openxo/goxc/config"
+	"github.com/openxo/goxc/core"
+	"github.com/openxo/goxc/platforms"
+	"log"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// a unit of work for one platform (and for one main dir, where relevant)
+type platformJob struct {
+	Platform platforms.Platform
+	MainDir  string
+}
+
+// the name used for log prefixes
+func (job platformJob) String() string {
+	return job.Platform.Name()
+}
+
+// one job per platform
+func jobsPerPlatform(destPlatforms []platforms.Platform) []platformJob {
+	jobs := []platformJob{}
+	for _, dest := range destPlatforms {
+		jobs = append(jobs, platformJob{dest, ""})
+	}
+	return jobs
+}
+
+// one job per platform, per main dir
+func jobsPerPlatformAndMainDir(destPlatforms []platforms.Platform, mainDirs []string) []platformJob {
+	jobs := []platformJob{}
+	for _, dest := range destPlatforms {
+		for _, mainDir := range mainDirs {
+			jobs = append(jobs, platformJob{dest, mainDir})
+		}
+	}
+	return jobs
+}
+
+// number of jobs to run at once.
+// Defaults to BuildSettings.Processors, or failing that, the number of CPUs. Always 1 for a dry run.
+func getParallelism(settings config.Settings) int {
+	//a dry run is recorded one job at a time, to keep the plan in order
+	if core.IsDryRun() {
+		return 1
+	}
+	if settings.Parallelism > 0 {
+		return settings.Parallelism
+	}
+	if settings.BuildSettings != nil && settings.BuildSettings.Processors != nil && *settings.BuildSettings.Processors > 0 {
+		return *settings.BuildSettings.Processors
+	}
+	return runtime.NumCPU()
+}
+
+// a logger prefixed with the task & platform, e.g. '[goxc:xc:linux_arm] '
+func newJobLogger(taskName string, job platformJob) *log.Logger {
+	return log.New(os.Stderr, "[goxc:"+taskName+":"+job.String()+"] ", log.Flags())
+}
+
+// Runs the given jobs using a bounded pool of workers.
+// After the first failure, no more jobs are started (jobs already running are allowed to finish), and that error is returned.
+// 0.11.x with KeepGoing, all jobs are run and all failures are returned (as TaskErrors).
+func runPlatformJobs(tp TaskParams, taskName string, jobs []platformJob, f func(job platformJob, logger *log.Logger) error) error {
+	workers := getParallelism(tp.Settings)
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+	if tp.Settings.IsVerbose() {
+		log.Printf("Running %d job(s) for task '%s', %d at a time", len(jobs), taskName, workers)
+	}
+	jobChan := make(chan platformJob)
+	cancel := make(chan bool)
+	errs := TaskErrors{}
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobChan {
+				//0.11.x events for CI
+				start := time.Now()
+				core.EmitEvent(core.Event{Type: core.EVENT_PLATFORM_START, Task: taskName, Platform: job.String(), MainDir: job.MainDir})
+				err := f(job, newJobLogger(taskName, job))
+				emitPlatformFinish(taskName, job, start, err)
+				if err != nil {
+					lock.Lock()
+					errs = append(errs, TaskError{taskName, job.String(), job.MainDir, err})
+					if len(errs) == 1 && !tp.Settings.KeepGoing {
+						close(cancel)
+					}
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+	//feed the workers until done or cancelled
+feed:
+	for _, job := range jobs {
+		select {
+		case <-cancel:
+			break feed
+		default:
+		}
+		select {
+		case jobChan <- job:
+		case <-cancel:
+			break feed
+		}
+	}
+	select {
+	case <-cancel:
+		log.Printf("Cancelled remaining jobs for task '%s'", taskName)
+	default:
+	}
+	close(jobChan)
+	wg.Wait()
+	if len(errs) == 0 {
+		return nil
+	}
+	if !tp.Settings.KeepGoing {
+		return errs[0]
+	}
+	return errs
+}
+
+func emitPlatformFinish(taskName string, job platformJob, start time.Time, err error) {
+	event := core.Event{Type: core.EVENT_PLATFORM_FINISH, Task: taskName, Platform: job.String(), MainDir: job.MainDir, Status: core.EVENT_STATUS_OK, Duration: core.MillisSince(start)}
+	if err != nil {
+		event.Status = core.EVENT_STATUS_FAILED
+		event.Error = err.Error()
+	}
+	core.EmitEvent(event)
+}
@@ -0,0 +1,30 @@
+package tasks
+
+import (
+	"errors"
+	"github.com/openxo/goxc/config"
+	"github.com/openxo/goxc/platforms"
+	"log"
+	"testing"
+)
+
+func TestRunPlatformJobsKeepGoing(t *testing.T) {
+	jobs := jobsPerPlatform([]platforms.Platform{{Os: platforms.LINUX, Arch: platforms.X86}, {Os: platforms.LINUX, Arch: platforms.ARM}, {Os: platforms.WINDOWS, Arch: platforms.X86}})
+	failing := func(job platformJob, logger *log.Logger) error {
+		if job.Platform.Os == platforms.LINUX {
+			return errors.New("failed")
+		}
+		return nil
+	}
+	tp := TaskParams{Settings: config.Settings{Parallelism: 1, KeepGoing: true}}
+	err := runPlatformJobs(tp, "test", jobs, failing)
+	errs, ok := err.(TaskErrors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("expected 2 TaskErrors, got %v", err)
+	}
+	tp.Settings.KeepGoing = false
+	err = runPlatformJobs(tp, "test", jobs, failing)
+	if _, ok := err.(TaskError); !ok {
+		t.Fatalf("expected a single TaskError, got %v", err)
+	}
+}
@@ -26,9 +26,7 @@ import (
 	"github.com/openxo/goxc/core"
 	"github.com/openxo/goxc/platforms"
 	"github.com/openxo/goxc/typeutils"
-	"io/ioutil"
 	"log"
-	"os"
 	"path/filepath"
 	//"strings"
 )
@@ -39,29 +37,54 @@ func init() {
 		TASK_PKG_BUILD,
 		"Build a binary package. Currently only supports .deb format for Debian/Ubuntu Linux.",
 		runTaskPkgBuild,
-		map[string]interface{}{"metadata": map[string]interface{}{"maintainer": "unknown"}, "metadata-deb": map[string]interface{}{"Depends": ""}, "rmtemp": true, "armarch": ""}})
+		map[string]interface{}{"metadata": map[string]interface{}{"maintainer": "unknown"}, "metadata-deb": map[string]interface{}{"Depends": ""}, "rmtemp": true, "armarch": ""},
+		[]string{TASK_XC}})
 }
 
 func runTaskPkgBuild(tp TaskParams) (err error) {
-	for _, dest := range tp.DestPlatforms {
-		err := pkgBuildPlat(dest.Os, dest.Arch, tp)
+	//0.11.x packages are built concurrently
+	return runPlatformJobs(tp, TASK_PKG_BUILD, jobsPerPlatform(tp.DestPlatforms), func(job platformJob, logger *log.Logger) error {
+		//0.11.x incremental builds
+		if tp.state.isUpToDate(TASK_PKG_BUILD, job) {
+			logger.Printf("Up to date. Skipping (use -rebuild to package anyway)")
+			return nil
+		}
+		outputs, err := pkgBuildPlat(logger, job.Platform, tp)
 		if err != nil {
-			log.Printf("Error: %v", err)
+			logger.Printf("Error: %v", err)
+			return err
 		}
-	}
-	return
+		tp.state.record(TASK_PKG_BUILD, job, outputs)
+		for _, output := range outputs {
+			err = tp.artifacts.register(ARTIFACT_PACKAGE, TASK_PKG_BUILD, job.Platform, "", output)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
-func pkgBuildPlat(destOs, destArch string, tp TaskParams) (err error) {
-	if destOs == platforms.LINUX {
+// returns the package file(s) built
+func pkgBuildPlat(logger *log.Logger, dest platforms.Platform, tp TaskParams) ([]string, error) {
+	if dest.Os == platforms.LINUX {
 		//TODO rpm
 		//TODO sdeb
-		return debBuild(destOs, destArch, tp)
+		err := ensureExes(logger, tp, dest)
+		if err != nil {
+			return nil, err
+		}
+		//0.11.x with any PlatformOverrides
+		targetFile, err := debBuild(logger, dest, tp.forPlatform(dest))
+		if err != nil {
+			return nil, err
+		}
+		return []string{targetFile}, nil
 	}
 	// TODO BSD ports?
 	// TODO Mac pkgs?
 	// TODO Windows - msi or something? Perhaps build an installer using 'https://github.com/jteeuwen/go-bindata' to pack the compressed executable
-	return nil
+	return []string{}, nil
 }
 
 func getDebControlFileContent(appName, maintainer, version, arch, armArchName, description string, metadataDeb map[string]interface{}) []byte {
@@ -93,7 +116,14 @@ func getDebArch(destArch string, armArchName string) string {
 	return architecture
 }
 
-func getArmArchName(settings config.Settings) string {
+// 0.11.x an arm variant takes precedence over the armarch & GOARM settings
+func getArmArchName(settings config.Settings, variant string) string {
+	switch variant {
+	case "v5":
+		return "armel"
+	case "v6", "v7":
+		return "armhf"
+	}
 	armArchName := settings.GetTaskSettingString(TASK_PKG_BUILD, "armarch")
 	if armArchName == "" {
 		//derive it from GOARM version:
@@ -107,66 +137,84 @@ func getArmArchName(settings config.Settings) string {
 	return armArchName
 }
 
-func debBuild(destOs, destArch string, tp TaskParams) (err error) {
+// 0.11.x returns the .deb filename
+func debBuild(logger *log.Logger, dest platforms.Platform, tp TaskParams) (targetFile string, err error) {
+	destArch := dest.Arch
 	metadata := tp.Settings.GetTaskSettingMap(TASK_PKG_BUILD, "metadata")
-	armArchName := getArmArchName(tp.Settings)
+	armArchName := getArmArchName(tp.Settings, dest.Variant)
 	metadataDeb := tp.Settings.GetTaskSettingMap(TASK_PKG_BUILD, "metadata-deb")
 	rmtemp := tp.Settings.GetTaskSettingBool(TASK_PKG_BUILD, "rmtemp")
 	debDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName()) //v0.8.1 dont use platform dir
-	tmpDir := filepath.Join(debDir, ".goxc-temp")
+	//0.11.x one temp dir per platform, because platforms are built concurrently
+	tmpDir := filepath.Join(debDir, ".goxc-temp", dest.Name())
 	if rmtemp {
-		defer os.RemoveAll(tmpDir)
+		defer func() {
+			core.RemoveAll(tmpDir)
+			//only removed once empty (i.e. by the last platform)
+			core.Remove(filepath.Dir(tmpDir))
+		}()
 	}
-	os.MkdirAll(tmpDir, 0755)
-	err = ioutil.WriteFile(filepath.Join(tmpDir, "debian-binary"), []byte("2.0\n"), 0644)
+	core.MkdirAll(tmpDir, 0755)
+	err = core.WriteFile(filepath.Join(tmpDir, "debian-binary"), []byte("2.0\n"), 0644)
 	if err != nil {
-		return err
+		return targetFile, err
 	}
 	description := "?"
 	if desc, keyExists := metadata["description"]; keyExists {
 		description, err = typeutils.ToString(desc, "description")
 		if err != nil {
-			return err
+			return targetFile, err
 		}
 	}
 	maintainer := "?"
 	if maint, keyExists := metadata["maintainer"]; keyExists {
 		maintainer, err = typeutils.ToString(maint, "maintainer")
 		if err != nil {
-			return err
+			return targetFile, err
 		}
 	}
 	controlContent := getDebControlFileContent(tp.AppName, maintainer, tp.Settings.GetFullVersionName(), destArch, armArchName, description, metadataDeb)
 	if tp.Settings.IsVerbose() {
-		log.Printf("Control file:\n%s", string(controlContent))
+		logger.Printf("Control file:\n%s", string(controlContent))
 	}
-	err = ioutil.WriteFile(filepath.Join(tmpDir, "control"), controlContent, 0644)
+	err = core.WriteFile(filepath.Join(tmpDir, "control"), controlContent, 0644)
 	if err != nil {
-		return err
+		return targetFile, err
 	}
-	err = archive.TarGz(filepath.Join(tmpDir, "control.tar.gz"), []archive.ArchiveItem{archive.ArchiveItem{FileSystemPath: filepath.Join(tmpDir, "control"), ArchivePath: "control"}})
+	err = archive.RunArchiver(archive.TarGz, filepath.Join(tmpDir, "control.tar.gz"), []archive.ArchiveItem{archive.ArchiveItem{FileSystemPath: filepath.Join(tmpDir, "control"), ArchivePath: "control"}})
 	if err != nil {
-		return err
+		return targetFile, err
 	}
 	//build
 	items := []archive.ArchiveItem{}
 
-	for _, mainDir := range tp.MainDirs {
-		exeName := filepath.Base(mainDir)
-		relativeBin := core.GetRelativeBin(destOs, destArch, exeName, false, tp.Settings.GetFullVersionName())
-		items = append(items, archive.ArchiveItem{FileSystemPath: filepath.Join(tp.OutDestRoot, relativeBin), ArchivePath: "/usr/bin/" + exeName})
+	for _, mainDir := range tp.packagedMainDirsFor(dest) {
+		//0.11.x binaries come from the artifact manifest
+		binPath := tp.binaryPath(dest, mainDir)
+		items = append(items, archive.ArchiveItem{FileSystemPath: binPath, ArchivePath: "/usr/bin/" + tp.exeName(mainDir)})
 	}
 	//TODO add resources to /usr/share/appName/
-	err = archive.TarGz(filepath.Join(tmpDir, "data.tar.gz"), items)
+	err = archive.RunArchiver(archive.TarGz, filepath.Join(tmpDir, "data.tar.gz"), items)
 	if err != nil {
-		return err
+		return targetFile, err
 	}
 
-	targetFile := filepath.Join(debDir, fmt.Sprintf("%s_%s_%s.deb", tp.AppName, tp.Settings.GetFullVersionName(), getDebArch(destArch, armArchName))) //goxc_0.5.2_i386.deb")
+	debArch := getDebArch(destArch, armArchName)
+	if dest.Variant != "" {
+		//0.11.x variants can share a debian architecture (e.g. v6 & v7 are both armhf)
+		debArch += "_" + dest.Variant
+	}
+	targetFile = filepath.Join(debDir, fmt.Sprintf("%s_%s_%s.deb", tp.AppName, tp.Settings.GetFullVersionName(), debArch)) //goxc_0.5.2_i386.deb")
 	inputs := [][]string{
 		[]string{filepath.Join(tmpDir, "debian-binary"), "debian-binary"},
 		[]string{filepath.Join(tmpDir, "control.tar.gz"), "control.tar.gz"},
 		[]string{filepath.Join(tmpDir, "data.tar.gz"), "data.tar.gz"}}
-	err = ar.ArForDeb(targetFile, inputs)
+	arEntries := []string{}
+	for _, input := range inputs {
+		arEntries = append(arEntries, input[1]+" <- "+input[0])
+	}
+	err = core.RunOp(core.Op{Kind: core.OP_ARCHIVE, Description: targetFile, Details: arEntries}, func() error {
+		return ar.ArForDeb(targetFile, inputs)
+	})
 	return
 }
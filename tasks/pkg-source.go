@@ -23,11 +23,12 @@ import (
 	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
 	"fmt"
 	"github.com/openxo/goxc/archive"
+	"github.com/openxo/goxc/core"
+	"github.com/openxo/goxc/executils"
 	"github.com/openxo/goxc/packaging/sdeb"
 	"github.com/openxo/goxc/platforms"
 	"github.com/openxo/goxc/typeutils"
-	"io/ioutil"
-	"os"
+	"log"
 	"path/filepath"
 	//"strings"
 )
@@ -42,7 +43,8 @@ func init() {
 		TASK_PKG_SOURCE,
 		"Build a source package. Currently only supports 'source deb' format for Debian/Ubuntu Linux.",
 		runTaskPkgSource,
-		map[string]interface{}{"metadata": map[string]interface{}{"maintainer": "unknown"}, "metadata-deb": map[string]interface{}{"Depends": "", "Build-Depends": "debhelper (>=4.0.0), golang-go, gcc"}, "rmtemp": true}})
+		map[string]interface{}{"metadata": map[string]interface{}{"maintainer": "unknown"}, "metadata-deb": map[string]interface{}{"Depends": "", "Build-Depends": "debhelper (>=4.0.0), golang-go, gcc"}, "rmtemp": true},
+		nil})
 }
 
 func runTaskPkgSource(tp TaskParams) (err error) {
@@ -89,7 +91,23 @@ func debSourceBuild(tp TaskParams) (err error) {
 	}
 	version := tp.Settings.GetFullVersionName()
 	arches := "any"
-	items, err := sdeb.SdebGetSourcesAsArchiveItems(tp.WorkingDirectory, tp.AppName+"-"+tp.Settings.GetFullVersionName())
+	rulesTemplate := sdeb.FILETEMPLATE_DEBIAN_RULES
+	var items []archive.ArchiveItem
+	if moduleRoot := core.GetModuleRoot(tp.WorkingDirectory); moduleRoot != "" {
+		//0.11.x modules are packaged with their dependencies vendored
+		rulesTemplate = sdeb.FILETEMPLATE_DEBIAN_RULES_MODULE
+		var vendorDir string
+		items, vendorDir, err = getModuleSourcesAsArchiveItems(moduleRoot, tp)
+		if vendorDir != "" && tp.Settings.GetTaskSettingBool(TASK_PKG_SOURCE, "rmtemp") {
+			defer func() {
+				core.RemoveAll(vendorDir)
+				//only removed once empty
+				core.Remove(filepath.Dir(vendorDir))
+			}()
+		}
+	} else {
+		items, err = sdeb.SdebGetSourcesAsArchiveItems(tp.WorkingDirectory, tp.AppName+"-"+tp.Settings.GetFullVersionName())
+	}
 	if err != nil {
 		return err
 	}
@@ -112,12 +130,12 @@ func debSourceBuild(tp TaskParams) (err error) {
 	//1. generate orig.tar.gz
 	//memcached_1.2.5.orig.tar.gz
 	destDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName(), "sdeb")
-	err = os.MkdirAll(destDir, 0777)
+	err = core.MkdirAll(destDir, 0777)
 	if err != nil {
 		return err
 	}
 	//TODO add/exclude resources to /usr/share
-	err = archive.TarGz(filepath.Join(destDir, tp.AppName+"_"+version+".orig.tar.gz"), items)
+	err = archive.RunArchiver(archive.TarGz, filepath.Join(destDir, tp.AppName+"_"+version+".orig.tar.gz"), items)
 	// []archive.ArchiveItem{archive.ArchiveItemFromFileSystem(tp.WorkingDirectory, "/usr/bin/"+tp.AppName)})
 	if err != nil {
 		return err
@@ -127,7 +145,10 @@ func debSourceBuild(tp TaskParams) (err error) {
 	//generate debian/control
 	controlData := getSourceDebControlFileContent(tp.AppName, maintainer, tp.Settings.GetFullVersionName(), arches, description, metadataDeb)
 	//generate debian/rules
-	rulesData := []byte(sdeb.FILETEMPLATE_DEBIAN_RULES)
+	rulesData, err := sdeb.GetRulesContent(rulesTemplate, tp.AppName)
+	if err != nil {
+		return err
+	}
 	sourceFormatData := []byte(sdeb.FILECONTENT_DEBIAN_SOURCE_FORMAT)
 	//generate debian/changelog
 	changelogData := []byte{}
@@ -135,7 +156,7 @@ func debSourceBuild(tp TaskParams) (err error) {
 	copyrightData := []byte{}
 	//generate debian/README.Debian
 	readmeData := []byte{}
-	err = archive.TarGz(filepath.Join(destDir, tp.AppName+"_"+version+".debian.tar.gz"),
+	err = archive.RunArchiver(archive.TarGz, filepath.Join(destDir, tp.AppName+"_"+version+".debian.tar.gz"),
 		[]archive.ArchiveItem{
 			archive.ArchiveItemFromBytes(changelogData, "debian/changelog"),
 			archive.ArchiveItemFromBytes(copyrightData, "debian/copyright"),
@@ -148,9 +169,42 @@ func debSourceBuild(tp TaskParams) (err error) {
 	}
 
 	//3. generate .dsc file
-	err = ioutil.WriteFile(filepath.Join(destDir, tp.AppName+"_"+version+".dsc"), controlData, 0644)
+	err = core.WriteFile(filepath.Join(destDir, tp.AppName+"_"+version+".dsc"), controlData, 0644)
+	if err != nil {
+		return err
+	}
+	for _, ending := range []string{".orig.tar.gz", ".debian.tar.gz", ".dsc"} {
+		err = tp.artifacts.register(ARTIFACT_SOURCE_PACKAGE, TASK_PKG_SOURCE, platforms.Platform{}, "", filepath.Join(destDir, tp.AppName+"_"+version+ending))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	return err
+// The module's sources, plus its dependencies in 'vendor'.
+// If the module isn't already vendored, 'go mod vendor' writes the dependencies to a temp dir (leaving the module itself untouched), which is returned.
+// 0.11.x
+func getModuleSourcesAsArchiveItems(moduleRoot string, tp TaskParams) ([]archive.ArchiveItem, string, error) {
+	prefix := tp.AppName + "-" + tp.Settings.GetFullVersionName()
+	excludeDirs := []string{tp.OutDestRoot}
+	if exists, _ := core.FileExists(filepath.Join(moduleRoot, "vendor", "modules.txt")); exists {
+		log.Printf("Using the module's vendor directory")
+		items, err := sdeb.SdebGetModuleSourcesAsArchiveItems(moduleRoot, "", prefix, excludeDirs)
+		return items, "", err
+	}
+	tmpDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName(), sdeb.DIRNAME_TEMP)
+	vendorDir := filepath.Join(tmpDir, "vendor")
+	err := core.RemoveAll(vendorDir)
+	if err != nil {
+		return nil, "", err
+	}
+	err = executils.InvokeGo(moduleRoot, "mod", []string{"vendor", "-o", vendorDir}, []string{}, tp.Settings)
+	if err != nil {
+		return nil, "", err
+	}
+	items, err := sdeb.SdebGetModuleSourcesAsArchiveItems(moduleRoot, vendorDir, prefix, excludeDirs)
+	return items, vendorDir, err
 }
 
 func getSourceDebControlFileContent(appName, maintainer, version, arch, description string, metadataDeb map[string]interface{}) []byte {
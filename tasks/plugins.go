@@ -0,0 +1,299 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/openxo/goxc/config"
+	"github.com/openxo/goxc/core"
+	"github.com/openxo/goxc/platforms"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+const (
+	PLUGIN_PREFIX = "goxc-task-"
+
+	//types of structured output from a plugin
+	PLUGIN_MESSAGE_ARTIFACT = "artifact"
+	PLUGIN_MESSAGE_WARNING  = "warning"
+	PLUGIN_MESSAGE_ERROR    = "error"
+	PLUGIN_MESSAGE_LOG      = "log"
+
+	//longest line of plugin output
+	PLUGIN_MAX_LINE_LENGTH = 16 * 1024 * 1024
+)
+
+// External tasks (0.11.x)
+// A plugin is an executable, run as a task. It's either declared in config ("Plugins"), or found on the PATH as 'goxc-task-<name>'.
+// It receives a PluginInput as JSON on stdin. The same details are also given as GOXC_* environment variables.
+// Settings only include the plugin's own TaskSettings, and any secrets elsewhere are masked.
+// Each line of its stdout which is a JSON object with a 'Type' is treated as a PluginMessage. Any other output is just logged.
+// A non-zero exit status means the task failed.
+type PluginInput struct {
+	Task             string
+	Platforms        []platforms.Platform
+	MainDirs         []string
+	AppName          string
+	WorkingDirectory string
+	OutDestRoot      string
+	VersionDir       string
+	Settings         config.Settings
+	TaskSettings     map[string]interface{}
+	Artifacts        []Artifact
+}
+
+// Structured output from a plugin
+type PluginMessage struct {
+	//artifact, warning, error or log
+	Type    string
+	Message string `json:",omitempty"`
+	//e.g. linux_amd64 (optional)
+	Platform string `json:",omitempty"`
+	MainDir  string `json:",omitempty"`
+	//for artifacts. Path is relative to the version dir, unless absolute
+	Kind string `json:",omitempty"`
+	Path string `json:",omitempty"`
+}
+
+var (
+	pluginLock sync.Mutex
+	//plugin task name -> executable
+	pluginCommands = map[string]string{}
+)
+
+// Registers plugins as tasks: any declared in config, plus any 'goxc-task-<name>' executables on the PATH.
+// Plugins can't replace built-in tasks.
+func RegisterPlugins(settings config.Settings) {
+	pluginLock.Lock()
+	defer pluginLock.Unlock()
+	found := findPluginsOnPath(os.Getenv("PATH"))
+	for name, plugin := range settings.Plugins {
+		found[name] = plugin
+	}
+	for name, plugin := range found {
+		if plugin.Command == "" {
+			core.Warnf("plugin '%s' has no Command. Ignoring it", name)
+			continue
+		}
+		if _, isPlugin := pluginCommands[name]; !isPlugin {
+			if _, keyExists := allTasks[name]; keyExists {
+				core.Warnf("plugin '%s' (%s) has the same name as a built-in task. Ignoring plugin", name, plugin.Command)
+				continue
+			}
+		}
+		pluginCommands[name] = plugin.Command
+		description := plugin.Description
+		if description == "" {
+			description = "External task"
+		}
+		Register(Task{
+			name,
+			description + " (plugin: " + plugin.Command + ")",
+			pluginTaskFunc(name, plugin.Command),
+			nil,
+			plugin.Dependencies})
+	}
+}
+
+// 'goxc-task-*' executables on the given PATH. The first one found wins
+func findPluginsOnPath(path string) map[string]config.PluginSettings {
+	found := map[string]config.PluginSettings{}
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			continue
+		}
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, fi := range files {
+			if fi.IsDir() || !strings.HasPrefix(fi.Name(), PLUGIN_PREFIX) {
+				continue
+			}
+			name := strings.TrimPrefix(fi.Name(), PLUGIN_PREFIX)
+			if runtime.GOOS == platforms.WINDOWS {
+				if !strings.HasSuffix(strings.ToLower(name), ".exe") {
+					continue
+				}
+				name = name[:len(name)-len(".exe")]
+			} else if fi.Mode()&0111 == 0 {
+				continue
+			}
+			if _, keyExists := found[name]; name != "" && !keyExists {
+				found[name] = config.PluginSettings{Command: filepath.Join(dir, fi.Name())}
+			}
+		}
+	}
+	return found
+}
+
+func pluginTaskFunc(name, command string) func(TaskParams) error {
+	return func(tp TaskParams) error {
+		return runPlugin(name, command, tp)
+	}
+}
+
+func runPlugin(name, command string, tp TaskParams) error {
+	input := PluginInput{
+		Task:             name,
+		Platforms:        tp.DestPlatforms,
+		MainDirs:         tp.MainDirs,
+		AppName:          tp.AppName,
+		WorkingDirectory: tp.WorkingDirectory,
+		OutDestRoot:      tp.OutDestRoot,
+		VersionDir:       filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName()),
+		Settings:         pluginSettings(tp.Settings, name),
+		TaskSettings:     tp.Settings.TaskSettings[name],
+		Artifacts:        tp.artifacts.list(),
+	}
+	stdin, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(command)
+	cmd.Dir = tp.WorkingDirectory
+	cmd.Env = append(os.Environ(), pluginEnv(input)...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	logger := log.New(os.Stderr, "[goxc:"+name+"] ", log.Flags())
+	cmd.Stderr = os.Stderr
+	failures := TaskErrors{}
+	err = core.RunOp(core.Op{Kind: core.OP_EXEC, Description: command, Details: []string{"plugin task '" + name + "'"}}, func() error {
+		err := cmd.Start()
+		if err != nil {
+			return err
+		}
+		failures, err = readPluginOutput(name, stdout, logger, tp)
+		//if reading stopped early, the plugin mustn't block on a full pipe
+		io.Copy(ioutil.Discard, stdout)
+		if waitErr := cmd.Wait(); waitErr != nil {
+			return waitErr
+		}
+		return err
+	})
+	if len(failures) > 0 {
+		//0.11.x per-platform failures, so that KeepGoing can carry on with other platforms
+		return failures
+	}
+	if err != nil {
+		return fmt.Errorf("plugin '%s' failed: %v", name, err)
+	}
+	return nil
+}
+
+// The settings given to a plugin: just its own TaskSettings (other tasks' settings can hold their secrets, e.g. bintray's apikey),
+// with any secrets elsewhere (e.g. in Env) masked.
+func pluginSettings(settings config.Settings, name string) config.Settings {
+	taskSettings := map[string]map[string]interface{}{}
+	if own, keyExists := settings.TaskSettings[name]; keyExists {
+		taskSettings[name] = own
+	}
+	settings.TaskSettings = nil
+	masked := config.Settings{}
+	data, err := json.Marshal(settings)
+	if err == nil {
+		err = json.Unmarshal([]byte(config.MaskSecrets(string(data))), &masked)
+	}
+	if err != nil {
+		//fall back to the version details only
+		masked = config.Settings{PackageVersion: settings.PackageVersion, BranchName: settings.BranchName, PrereleaseInfo: settings.PrereleaseInfo, BuildName: settings.BuildName}
+	}
+	masked.TaskSettings = taskSettings
+	return masked
+}
+
+// GOXC_* environment variables, for plugins which would rather not parse JSON
+func pluginEnv(input PluginInput) []string {
+	return []string{
+		"GOXC_TASK=" + input.Task,
+		"GOXC_PLATFORMS=" + strings.Join(platformNames(input.Platforms), " "),
+		"GOXC_MAIN_DIRS=" + strings.Join(input.MainDirs, string(os.PathListSeparator)),
+		"GOXC_APP_NAME=" + input.AppName,
+		"GOXC_WORKING_DIRECTORY=" + input.WorkingDirectory,
+		"GOXC_OUT_DEST_ROOT=" + input.OutDestRoot,
+		"GOXC_VERSION_DIR=" + input.VersionDir,
+		"GOXC_VERSION=" + input.Settings.GetFullVersionName(),
+	}
+}
+
+// reads the plugin's stdout, acting on any structured output. Returns any reported failures
+func readPluginOutput(name string, r io.Reader, logger *log.Logger, tp TaskParams) (TaskErrors, error) {
+	failures := TaskErrors{}
+	scanner := bufio.NewScanner(r)
+	//allow long lines (e.g. big JSON messages)
+	scanner.Buffer(make([]byte, 64*1024), PLUGIN_MAX_LINE_LENGTH)
+	for scanner.Scan() {
+		line := scanner.Text()
+		message := PluginMessage{}
+		if !strings.HasPrefix(strings.TrimSpace(line), "{") || json.Unmarshal([]byte(line), &message) != nil || message.Type == "" {
+			logger.Print(line)
+			continue
+		}
+		switch message.Type {
+		case PLUGIN_MESSAGE_ARTIFACT:
+			artifactPath := message.Path
+			if !filepath.IsAbs(artifactPath) {
+				artifactPath = filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName(), artifactPath)
+			}
+			kind := message.Kind
+			if kind == "" {
+				kind = ARTIFACT_ARCHIVE
+			}
+			err := tp.artifacts.register(kind, name, findPlatform(tp.DestPlatforms, message.Platform), message.MainDir, artifactPath)
+			if err != nil {
+				return failures, err
+			}
+		case PLUGIN_MESSAGE_WARNING:
+			core.Warnf("%s: %s", name, message.Message)
+		case PLUGIN_MESSAGE_ERROR:
+			logger.Printf("Error: %s", message.Message)
+			failures = append(failures, TaskError{name, message.Platform, message.MainDir, errors.New(message.Message)})
+		case PLUGIN_MESSAGE_LOG:
+			logger.Print(message.Message)
+		default:
+			logger.Printf("Unrecognised message type '%s': %s", message.Type, line)
+		}
+	}
+	return failures, scanner.Err()
+}
+
+// the platform with the given name (as per platformJob.String()). Empty if not found
+func findPlatform(destPlatforms []platforms.Platform, name string) platforms.Platform {
+	for _, dest := range destPlatforms {
+		if (platformJob{dest, ""}).String() == name {
+			return dest
+		}
+	}
+	return platforms.Platform{}
+}
@@ -0,0 +1,69 @@
+package tasks
+
+import (
+	"github.com/openxo/goxc/config"
+	"github.com/openxo/goxc/platforms"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFindPluginsOnPath(t *testing.T) {
+	if runtime.GOOS == platforms.WINDOWS {
+		t.Skip("uses unix file modes")
+	}
+	dir, err := ioutil.TempDir("", "goxc-plugins")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "goxc-task-upload"), []byte("#!/bin/sh\n"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "goxc-task-notexecutable"), []byte("#!/bin/sh\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "other"), []byte("#!/bin/sh\n"), 0755)
+	found := findPluginsOnPath(dir)
+	if len(found) != 1 || found["upload"].Command != filepath.Join(dir, "goxc-task-upload") {
+		t.Errorf("Unexpected plugins %v", found)
+	}
+}
+
+func TestReadPluginOutput(t *testing.T) {
+	linux := platforms.Platform{Os: platforms.LINUX, Arch: platforms.AMD64}
+	tp := TaskParams{DestPlatforms: []platforms.Platform{linux}, Settings: config.Settings{}}
+	output := "plain output\n" +
+		`{"Type":"log","Message":"hello"}` + "\n" +
+		`{"Type":"error","Platform":"linux_amd64","Message":"upload failed"}` + "\n"
+	logger := log.New(ioutil.Discard, "", 0)
+	failures, err := readPluginOutput("upload", strings.NewReader(output), logger, tp)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(failures) != 1 || failures[0].Platform != "linux_amd64" || failures[0].Err.Error() != "upload failed" {
+		t.Errorf("Unexpected failures %v", failures)
+	}
+}
+
+func TestReadPluginOutputLongLine(t *testing.T) {
+	tp := TaskParams{Settings: config.Settings{}}
+	output := strings.Repeat("x", 100*1024) + "\n" + `{"Type":"error","Message":"failed"}` + "\n"
+	failures, err := readPluginOutput("upload", strings.NewReader(output), log.New(ioutil.Discard, "", 0), tp)
+	if err != nil || len(failures) != 1 {
+		t.Errorf("Unexpected result %v (%v)", failures, err)
+	}
+}
+
+func TestPluginSettings(t *testing.T) {
+	settings := config.Settings{PackageVersion: "1.0", TaskSettings: map[string]map[string]interface{}{
+		"bintray": {"apikey": "bintray-secret"},
+		"upload":  {"url": "https://example.com"}}}
+	pluginSettings := pluginSettings(settings, "upload")
+	if _, keyExists := pluginSettings.TaskSettings["bintray"]; keyExists {
+		t.Errorf("Other tasks' settings should not be passed to a plugin: %v", pluginSettings.TaskSettings)
+	}
+	if pluginSettings.GetTaskSettingString("upload", "url") != "https://example.com" || pluginSettings.PackageVersion != "1.0" {
+		t.Errorf("Unexpected plugin settings: %+v", pluginSettings)
+	}
+}
@@ -19,11 +19,9 @@ package tasks
 import (
 	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
 	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
-	"github.com/openxo/goxc/config"
 	"github.com/openxo/goxc/core"
 	"io/ioutil"
 	"log"
-	"os"
 	"path/filepath"
 )
 
@@ -33,17 +31,23 @@ func init() {
 		"rmbin",
 		"delete binary. Normally runs after 'archive' task to reduce size of output dir.",
 		runTaskRmBin,
+		nil,
 		nil})
 }
 
 func runTaskRmBin(tp TaskParams) error {
 	for _, dest := range tp.DestPlatforms {
-		for _, mainDir := range tp.MainDirs {
-			exeName := filepath.Base(mainDir)
-			err := rmBinPlat(dest.Os, dest.Arch, exeName, tp.OutDestRoot, tp.Settings)
+		for _, mainDir := range tp.mainDirsFor(dest) {
+			//0.11.x binary location comes from the artifact manifest
+			binPath := tp.binaryPath(dest, mainDir)
+			err := rmBinPlat(binPath)
 			if err != nil {
 				//todo - add a force option?
 				log.Printf("%v", err)
+			} else {
+				//0.11.x so that an up-to-date 'xc' isn't re-run just to replace it
+				tp.state.recordRemoved(TASK_XC, platformJob{dest, mainDir})
+				tp.artifacts.unregister(binPath)
 			}
 		}
 	}
@@ -51,11 +55,13 @@ func runTaskRmBin(tp TaskParams) error {
 	return nil
 }
 
-func rmBinPlat(goos, arch, exeName, outDestRoot string, settings config.Settings) error {
-	relativeBin := core.GetRelativeBin(goos, arch, exeName, false, settings.GetFullVersionName())
-	binPath := filepath.Join(outDestRoot, relativeBin)
-	err := os.Remove(binPath)
-	if err != nil {
+func rmBinPlat(binPath string) error {
+	//0.11.x already removed (e.g. 'xc' was skipped as up-to-date)
+	if exists, err := core.FileExists(binPath); !exists && err == nil && !core.IsDryRun() {
+		return nil
+	}
+	err := core.Remove(binPath)
+	if err != nil || core.IsDryRun() {
 		return err
 	}
 	//if empty, remove dir
@@ -65,7 +71,7 @@ func rmBinPlat(goos, arch, exeName, outDestRoot string, settings config.Settings
 		return err
 	}
 	if len(files) < 1 {
-		err = os.Remove(binDir)
+		err = core.Remove(binDir)
 	}
 	return err
 }
@@ -33,7 +33,8 @@ func init() {
 		"tag repository according to package version.",
 		tag,
 		map[string]interface{}{
-			"vcs": "git", "prefix": "v"}}) //TODO support other vcs's
+			"vcs": "git", "prefix": "v"}, //TODO support other vcs's
+		nil})
 }
 
 func tag(tp TaskParams) error {
@@ -48,13 +49,7 @@ func tag(tp TaskParams) error {
 		if err != nil {
 			return err
 		}
-		err = cmd.Start()
-		if err != nil {
-			return err
-		} else {
-			err = cmd.Wait()
-			return err
-		}
+		return executils.RunCmd(cmd, []string{})
 	} else {
 		return errors.New("Only 'git' is supported at this stage")
 	}
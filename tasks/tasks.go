@@ -25,8 +25,12 @@ import (
 	"github.com/openxo/goxc/core"
 	"github.com/openxo/goxc/platforms"
 	"github.com/openxo/goxc/source"
+	"github.com/openxo/goxc/typeutils"
 	"log"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 const (
@@ -67,10 +71,11 @@ var (
 	TASKS_VALIDATE = []string{TASK_GO_VET, TASK_GO_TEST}
 	TASKS_COMPILE  = []string{TASK_GO_INSTALL, TASK_XC, TASK_CODESIGN, TASK_COPY_RESOURCES}
 	TASKS_ARCHIVE  = []string{TASK_ARCHIVE_ZIP, TASK_ARCHIVE_TAR_GZ}
-	TASKS_PACKAGE  = []string{TASK_ARCHIVE_ZIP, TASK_ARCHIVE_TAR_GZ, TASK_PKG_BUILD, TASK_REMOVE_BIN, TASK_DOWNLOADS_PAGE}
-	TASKS_DEFAULT  = append(append(append([]string{}, TASKS_VALIDATE...), TASKS_COMPILE...), TASKS_PACKAGE...)
-	TASKS_OTHER    = []string{TASK_BUILD_TOOLCHAIN, TASK_GO_FMT}
-	TASKS_ALL      = append(append([]string{}, TASKS_OTHER...), TASKS_DEFAULT...)
+	TASKS_PACKAGE  = []string{TASKALIAS_ARCHIVE, TASK_PKG_BUILD, TASK_REMOVE_BIN, TASK_DOWNLOADS_PAGE}
+	//0.11.x aliases can contain aliases
+	TASKS_DEFAULT = []string{TASKALIAS_VALIDATE, TASKALIAS_COMPILE, TASKALIAS_PACKAGE}
+	TASKS_OTHER   = []string{TASK_BUILD_TOOLCHAIN, TASK_GO_FMT}
+	TASKS_ALL     = append(append([]string{}, TASKS_OTHER...), TASKALIAS_DEFAULT)
 )
 
 // Parameter object passed to a task.
@@ -80,19 +85,31 @@ type TaskParams struct {
 	AppName                       string
 	WorkingDirectory, OutDestRoot string
 	Settings                      config.Settings
+	//0.11.x for incremental builds
+	state *buildState
+	//0.11.x artifacts produced (in this run or earlier runs)
+	artifacts *artifactManifest
+}
+
+// 0.11.x the task params for one platform, with any PlatformOverrides applied to the settings
+func (tp TaskParams) forPlatform(dest platforms.Platform) TaskParams {
+	tp.Settings = tp.Settings.ForPlatform(dest)
+	return tp
 }
 
 // A task is basically a user-defined function given a unique name, plus some 'default settings'
+// 0.11.x tasks declare the tasks (or aliases) they depend on. These are run first.
 type Task struct {
 	Name            string
 	Description     string
 	f               func(TaskParams) error
 	DefaultSettings map[string]interface{}
+	Dependencies    []string
 }
 
 var (
 	allTasks = make(map[string]Task)
-	//Aliases are one or more tasks, in a specific order.
+	//Aliases are one or more tasks (or aliases), in a specific order.
 	Aliases = map[string][]string{
 		TASKALIAS_CLEAN:    TASKS_CLEAN,
 		TASKALIAS_VALIDATE: TASKS_VALIDATE,
@@ -106,20 +123,8 @@ var (
 // Register a task for use by goxc. Call from an 'init' function
 func Register(task Task) {
 	allTasks[task.Name] = task
-}
-
-// resolve aliases into tasks
-// TODO recurse. (currently aliases are only lists of tasks, not of aliases). Recursion would enable the extra flexibility
-func ResolveAliases(tasks []string) []string {
-	ret := []string{}
-	for _, taskName := range tasks {
-		if aliasTasks, keyExists := Aliases[taskName]; keyExists {
-			ret = append(ret, aliasTasks...)
-		} else {
-			ret = append(ret, taskName)
-		}
-	}
-	return ret
+	//0.11.x so that config files can be validated
+	config.RegisterTaskDefaults(task.Name, task.DefaultSettings)
 }
 
 // list all available tasks
@@ -131,30 +136,73 @@ func ListTasks() []Task {
 	return tasks
 }
 
+// names of all tasks and aliases (including user-defined aliases)
+func taskNames(settings config.Settings) []string {
+	names := []string{}
+	for name := range allTasks {
+		names = append(names, name)
+	}
+	for name := range Aliases {
+		names = append(names, name)
+	}
+	for name := range settings.Aliases {
+		names = append(names, name)
+	}
+	return names
+}
+
 // run all given tasks
-func RunTasks(workingDirectory string, destPlatforms []platforms.Platform, settings config.Settings) {
+// 0.11.x returns an error if any task failed (TaskErrors if any tasks ran).
+func RunTasks(workingDirectory string, destPlatforms []platforms.Platform, settings config.Settings) (err error) {
+	//0.11.x events for CI
+	runStart := time.Now()
+	core.EmitEvent(core.Event{Type: core.EVENT_RUN_START, Message: workingDirectory})
+	defer func() {
+		event := core.Event{Type: core.EVENT_RUN_FINISH, Status: core.EVENT_STATUS_OK, Duration: core.MillisSince(runStart)}
+		if err != nil {
+			event.Status = core.EVENT_STATUS_FAILED
+			event.Error = err.Error()
+		}
+		core.EmitEvent(event)
+	}()
 	log.Printf("Go root: %s", settings.GoRoot)
 	if settings.IsVerbose() {
 		log.Printf("looping through each platform")
 	}
 	appName := core.GetAppName(workingDirectory)
+	//0.11.x fixed timestamps for reproducible builds
+	if settings.BuildSettings != nil && settings.BuildSettings.Reproducible != nil && *settings.BuildSettings.Reproducible {
+		err = setReproducibleTime(workingDirectory)
+		if err != nil {
+			return err
+		}
+		defer core.SetReproducibleTime(nil)
+	}
 
 	outDestRoot := core.GetOutDestRoot(appName, settings.ArtifactsDest, workingDirectory)
-	defer log.SetPrefix("[goxc] ")
-	exclusions := ResolveAliases(settings.TasksExclude)
-	appends := ResolveAliases(settings.TasksAppend)
-	mains := ResolveAliases(settings.Tasks)
-	all := ResolveAliases(settings.TasksPrepend)
-	//log.Printf("prepending %v", all)
-	all = append(all, mains...)
-	all = append(all, appends...)
-
+	//0.11.x external tasks
+	RegisterPlugins(settings)
+	exclusions, err := ResolveAllAliases(settings.TasksExclude, settings)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return err
+	}
+	//no tasks specified: the 'default' alias (resolved here rather than filled in, so that it's not written to config)
+	tasksToResolve := settings.Tasks
+	if len(tasksToResolve) == 0 {
+		tasksToResolve = []string{TASKALIAS_DEFAULT}
+	}
+	all, err := ResolveAllAliases(append(append(append([]string{}, settings.TasksPrepend...), tasksToResolve...), settings.TasksAppend...), settings)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return err
+	}
+	//0.11.x add dependencies & order the tasks accordingly.
 	//exclude by resolved task names (not by aliases)
-	tasksToRun := []string{}
-	for _, taskName := range all {
-		if !core.ContainsString(exclusions, taskName) {
-			tasksToRun = append(tasksToRun, taskName)
-		}
+	tasksToRun, err := orderTasks(allTasks, all, exclusions)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return err
 	}
 	//0.6 check all tasks are valid before continuing
 	for _, taskName := range tasksToRun {
@@ -165,8 +213,12 @@ func RunTasks(workingDirectory string, destPlatforms []platforms.Platform, setti
 			if e, _ := core.FileExists(taskName); e {
 				log.Printf("'%s' looks like a directory, not a task - specify 'working directory' with -wd option", taskName)
 			}
+			if suggestion := typeutils.ClosestMatch(taskName, taskNames(settings)); suggestion != "" {
+				log.Printf("Task %s does NOT exist! Did you mean '%s'?", taskName, suggestion)
+				return fmt.Errorf("Task %s does NOT exist! Did you mean '%s'?", taskName, suggestion)
+			}
 			log.Printf("Task %s does NOT exist!", taskName)
-			return
+			return fmt.Errorf("Task %s does NOT exist!", taskName)
 		}
 	}
 	var mainDirs []string
@@ -174,34 +226,160 @@ func RunTasks(workingDirectory string, destPlatforms []platforms.Platform, setti
 		log.Printf("Toolchain task only - not searching for main dirs")
 		//mainDirs = []string{workingDirectory}
 	} else {
-		var err error
 		excludes := core.ParseCommaGlobs(settings.MainDirsExclude)
-		mainDirs, err = source.FindMainDirs(workingDirectory, excludes)
+		mainDirs, err = findMainDirs(workingDirectory, excludes, settings)
 		if err != nil || len(mainDirs) == 0 {
-			log.Printf("Warning: could not establish list of main dirs. Using working directory")
+			core.Warnf("could not establish list of main dirs. Using working directory")
 			mainDirs = []string{workingDirectory}
 		} else {
 			log.Printf("Found 'main package' dirs (len %d): %v", len(mainDirs), mainDirs)
 		}
+		//0.11.x per-binary settings
+		warnUnmatchedBinaries(TaskParams{MainDirs: mainDirs, WorkingDirectory: workingDirectory, Settings: settings})
 	}
 	log.Printf("Running tasks: %v on packages %v", tasksToRun, mainDirs)
+	//0.11.x incremental builds
+	state := loadBuildState(workingDirectory, outDestRoot, settings)
+	//0.11.x artifact manifest
+	artifacts := loadArtifactManifest(filepath.Join(outDestRoot, settings.GetFullVersionName()))
+	//0.11.x for dry runs
+	core.RecordOp(core.Op{Kind: core.OP_PLAN, Description: "tasks", Details: tasksToRun})
+	core.RecordOp(core.Op{Kind: core.OP_PLAN, Description: "platforms", Details: platformNames(destPlatforms)})
+	core.RecordOp(core.Op{Kind: core.OP_PLAN, Description: "main dirs", Details: mainDirs})
+	//0.11.x no longer using log.SetPrefix (platforms run concurrently, each with its own prefixed logger)
+	failures := TaskErrors{}
+	//0.11.x with KeepGoing, tasks which depend on a failed task are skipped (or just the failed platforms are skipped)
+	failedTasks := []string{}
+	failedPlatforms := map[string][]string{}
 	for _, taskName := range tasksToRun {
-		log.SetPrefix("[goxc:" + taskName + "] ")
-		err := runTask(taskName, destPlatforms, mainDirs, appName, workingDirectory, outDestRoot, settings)
+		taskPlatforms := destPlatforms
+		failedDep := ""
+		for _, dep := range transitiveDependencies(allTasks, taskName) {
+			if core.ContainsString(failedTasks, dep) {
+				failedDep = dep
+				break
+			}
+			taskPlatforms = removePlatforms(taskPlatforms, failedPlatforms[dep])
+		}
+		if failedDep != "" {
+			log.Printf("Skipping task %s because '%s' failed", taskName, failedDep)
+			skipErr := fmt.Errorf("skipped because '%s' failed", failedDep)
+			core.EmitEvent(core.Event{Type: core.EVENT_TASK_FINISH, Task: taskName, Status: core.EVENT_STATUS_SKIPPED, Error: skipErr.Error()})
+			failures = append(failures, TaskError{Task: taskName, Err: skipErr})
+			failedTasks = append(failedTasks, taskName)
+			continue
+		}
+		if len(taskPlatforms) < len(destPlatforms) {
+			log.Printf("Task %s is skipping %d platform(s) which failed earlier", taskName, len(destPlatforms)-len(taskPlatforms))
+		}
+		log.Printf("Running task %s", taskName)
+		taskStart := time.Now()
+		core.EmitEvent(core.Event{Type: core.EVENT_TASK_START, Task: taskName, Message: strings.Join(platformNames(taskPlatforms), " ")})
+		err := runTask(taskName, taskPlatforms, mainDirs, appName, workingDirectory, outDestRoot, settings, state, artifacts)
+		emitTaskFinish(taskName, taskStart, err)
+		//save whatever succeeded
+		if saveErr := state.save(); saveErr != nil {
+			log.Printf("Could not save build state: %v", saveErr)
+		}
+		if saveErr := artifacts.save(); saveErr != nil {
+			log.Printf("Could not save artifact manifest: %v", saveErr)
+		}
 		if err != nil {
-			// TODO: implement 'force' option.
-			log.Printf("Stopping after '%s' failed with error '%v'", taskName, err)
-			return
+			taskFailures := toTaskErrors(taskName, err)
+			failures = append(failures, taskFailures...)
+			for _, failure := range taskFailures {
+				if failure.Platform == "" {
+					failedTasks = append(failedTasks, taskName)
+				} else {
+					failedPlatforms[taskName] = append(failedPlatforms[taskName], failure.Platform)
+				}
+			}
+			if !settings.KeepGoing {
+				log.Printf("Stopping after '%s' failed with error '%v'", taskName, err)
+				break
+			}
+			log.Printf("Task %s failed with error '%v'. Continuing", taskName, err)
 		} else {
 			log.Printf("Task %s succeeded", taskName)
 		}
 	}
+	if len(failures) > 0 {
+		failures.PrintSummary(os.Stderr)
+		return failures
+	}
+	return nil
+}
+
+func emitTaskFinish(taskName string, start time.Time, err error) {
+	event := core.Event{Type: core.EVENT_TASK_FINISH, Task: taskName, Status: core.EVENT_STATUS_OK, Duration: core.MillisSince(start)}
+	if err != nil {
+		event.Status = core.EVENT_STATUS_FAILED
+		event.Error = err.Error()
+		//one error event per failure
+		for _, failure := range toTaskErrors(taskName, err) {
+			core.EmitEvent(core.Event{Type: core.EVENT_ERROR, Task: taskName, Platform: failure.Platform, MainDir: failure.MainDir, Error: failure.Err.Error()})
+		}
+	}
+	core.EmitEvent(event)
+}
+
+// 0.11.x fixes the time used for reproducible builds (see core.GetSourceDateEpoch)
+func setReproducibleTime(workingDirectory string) error {
+	t, err := core.GetSourceDateEpoch(workingDirectory)
+	if err != nil {
+		return fmt.Errorf("reproducible builds need a fixed time: %v", err)
+	}
+	log.Printf("Reproducible build. Using timestamp %s", t.Format(time.RFC3339))
+	core.SetReproducibleTime(&t)
+	return nil
+}
+
+// 0.11.x in module mode, main packages are listed by the go tool (by import path), falling back to parsing the source
+func findMainDirs(workingDirectory string, excludes []string, settings config.Settings) ([]string, error) {
+	if core.GetModuleRoot(workingDirectory) != "" {
+		mainPackages, err := source.FindMainPackages(workingDirectory, filepath.Join(settings.GoRoot, "bin", "go"), excludes)
+		if err == nil && len(mainPackages) > 0 {
+			mainDirs := []string{}
+			for _, mainPackage := range mainPackages {
+				log.Printf("Main package %s (%s)", mainPackage.ImportPath, mainPackage.Dir)
+				mainDirs = append(mainDirs, mainPackage.Dir)
+			}
+			return mainDirs, nil
+		}
+		if err != nil {
+			core.Warnf("could not list main packages: %v", err)
+		}
+	}
+	return source.FindMainDirs(workingDirectory, excludes)
+}
+
+// platform names (as per platformJob.String())
+func platformNames(destPlatforms []platforms.Platform) []string {
+	names := []string{}
+	for _, dest := range destPlatforms {
+		names = append(names, platformJob{dest, ""}.String())
+	}
+	return names
+}
+
+// platforms not in the given list of names (as per platformJob.String())
+func removePlatforms(destPlatforms []platforms.Platform, names []string) []platforms.Platform {
+	if len(names) == 0 {
+		return destPlatforms
+	}
+	ret := []platforms.Platform{}
+	for _, dest := range destPlatforms {
+		if !core.ContainsString(names, platformJob{dest, ""}.String()) {
+			ret = append(ret, dest)
+		}
+	}
+	return ret
 }
 
 // run named task
-func runTask(taskName string, destPlatforms []platforms.Platform, mainDirs []string, appName, workingDirectory, outDestRoot string, settings config.Settings) error {
+func runTask(taskName string, destPlatforms []platforms.Platform, mainDirs []string, appName, workingDirectory, outDestRoot string, settings config.Settings, state *buildState, artifacts *artifactManifest) error {
 	if taskV, keyExists := allTasks[taskName]; keyExists {
-		tp := TaskParams{destPlatforms, mainDirs, appName, workingDirectory, outDestRoot, settings}
+		tp := TaskParams{destPlatforms, mainDirs, appName, workingDirectory, outDestRoot, settings, state, artifacts}
 		return taskV.f(tp)
 	}
 	log.Printf("Unrecognised task '%s'", taskName)
@@ -209,9 +387,6 @@ func runTask(taskName string, destPlatforms []platforms.Platform, mainDirs []str
 }
 
 func FillTaskSettingsDefaults(settings *config.Settings) {
-	if len(settings.Tasks) == 0 {
-		settings.Tasks = Aliases[TASKALIAS_DEFAULT]
-	}
 	if settings.TaskSettings == nil {
 		settings.TaskSettings = make(map[string]map[string]interface{})
 	}
@@ -33,14 +33,20 @@ import (
 func init() {
 	Register(Task{
 		"toolchain",
-		"Build toolchain. Make sure to run this each time you update go source.",
+		"Build toolchain. Make sure to run this each time you update go source. Only needed before Go 1.5.",
 		runTaskToolchain,
-		map[string]interface{}{"GOARM": "", "extra-env": []string{}, "no-clean": true}})
+		map[string]interface{}{"GOARM": "", "extra-env": []string{}, "no-clean": true},
+		nil})
 }
 
 func runTaskToolchain(tp TaskParams) error {
 	if len(tp.DestPlatforms) < 1 {
 		return errors.New("No valid platforms specified")
+	} else if !executils.IsLegacyToolchain(tp.Settings.GoRoot) {
+		//0.11.x
+		goVersion, _ := executils.GoVersion(tp.Settings.GoRoot)
+		log.Printf("No toolchain to build: '%s' cross-compiles using GOOS & GOARCH alone.", goVersion)
+		return nil
 	} else {
 		log.Printf("Please do NOT try to quit during a build-toolchain. This can leave your Go toolchain in a non-working state.")
 		busy := false
@@ -110,14 +116,9 @@ func buildToolchain(goos string, arch string, settings config.Settings) error {
 	}
 	log.Printf("Invoking '%v' from %s", executils.PrintableArgs(cmd.Args), cmd.Dir)
 	executils.RedirectIO(cmd)
-	err := cmd.Start()
+	err := executils.RunCmd(cmd, env)
 	if err != nil {
-		log.Printf("Build toolchain: Launch error: %s", err)
-		return err
-	}
-	err = cmd.Wait()
-	if err != nil {
-		log.Printf("Build Toolchain: wait error: %s", err)
+		log.Printf("Build Toolchain: error: %s", err)
 		return err
 	}
 	if settings.IsVerbose() {
@@ -0,0 +1,274 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	//Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	//see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"errors"
+	"fmt"
+	"github.com/openxo/goxc/archive"
+	"github.com/openxo/goxc/config"
+	"github.com/openxo/goxc/core"
+	"github.com/openxo/goxc/platforms"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const TASK_VERIFY_REPRODUCIBLE = "verify-reproducible"
+
+//runs automatically
+func init() {
+	Register(Task{
+		TASK_VERIFY_REPRODUCIBLE,
+		"Verify that builds are reproducible. Builds, archives & packages each platform twice, from separate copies of the source in separate temp dirs (each with its own build cache), and compares checksums. Always builds in 'Reproducible' mode.",
+		runTaskVerifyReproducible,
+		map[string]interface{}{"keep-temp": false},
+		nil})
+}
+
+func runTaskVerifyReproducible(tp TaskParams) error {
+	if len(tp.DestPlatforms) == 0 {
+		return errors.New("No valid platforms specified")
+	}
+	if _, isReproducible := core.GetReproducibleTime(); !isReproducible {
+		err := setReproducibleTime(tp.WorkingDirectory)
+		if err != nil {
+			return err
+		}
+		defer core.SetReproducibleTime(nil)
+	}
+	tmpRoot := filepath.Join(os.TempDir(), "goxc-"+TASK_VERIFY_REPRODUCIBLE)
+	if !core.IsDryRun() {
+		var err error
+		tmpRoot, err = ioutil.TempDir("", "goxc-"+TASK_VERIFY_REPRODUCIBLE)
+		if err != nil {
+			return err
+		}
+		if tp.Settings.GetTaskSettingBool(TASK_VERIFY_REPRODUCIBLE, "keep-temp") {
+			log.Printf("Builds are kept in %s", tmpRoot)
+		} else {
+			defer os.RemoveAll(tmpRoot)
+		}
+	}
+	//each build uses its own copy of the source, so that any embedded source paths differ
+	srcRoot, relativeDest, isGopath := verificationSourceTree(tp.WorkingDirectory)
+	buildNames := []string{"a", "b"}
+	for _, buildName := range buildNames {
+		copyRoot := filepath.Join(tmpRoot, buildName, relativeDest)
+		err := core.RunOp(core.Op{Kind: core.OP_WRITE, Description: copyRoot, Details: []string{"copy of " + srcRoot}}, func() error {
+			return copySourceTree(srcRoot, copyRoot, []string{tp.OutDestRoot, tmpRoot})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	//0.11.x platforms are verified concurrently
+	return runPlatformJobs(tp, TASK_VERIFY_REPRODUCIBLE, jobsPerPlatform(tp.DestPlatforms), func(job platformJob, logger *log.Logger) error {
+		checksums := []map[string]string{}
+		for _, buildName := range buildNames {
+			buildRoot := filepath.Join(tmpRoot, buildName)
+			vtp := tp.forVerification(srcRoot, filepath.Join(buildRoot, relativeDest), filepath.Join(buildRoot, job.String()))
+			if isGopath {
+				vtp.Settings.Env = append(vtp.Settings.Env, "GOPATH="+filepath.Join(buildRoot, "gopath")+string(os.PathListSeparator)+os.Getenv("GOPATH"))
+			}
+			buildChecksums, err := buildForVerification(logger, job.Platform, vtp)
+			if err != nil {
+				return err
+			}
+			checksums = append(checksums, buildChecksums)
+		}
+		if core.IsDryRun() {
+			return nil
+		}
+		err := compareChecksums(checksums[0], checksums[1])
+		if err == nil {
+			logger.Printf("Reproducible: %d artifact(s) matched", len(checksums[0]))
+		}
+		return err
+	})
+}
+
+// The source tree to copy for each build, & where to copy it (relative to the build's temp dir).
+// In module mode, the module root. In GOPATH mode, the working directory, at its import path inside a temporary GOPATH element
+func verificationSourceTree(workingDirectory string) (srcRoot, relativeDest string, isGopath bool) {
+	if moduleRoot := core.GetModuleRoot(workingDirectory); moduleRoot != "" {
+		return moduleRoot, "src", false
+	}
+	workingDirectory, _ = filepath.Abs(workingDirectory)
+	gopathSrc, err := filepath.Abs(filepath.Join(core.GetGoPathElement(workingDirectory), "src"))
+	if err == nil {
+		if importPath, err := filepath.Rel(gopathSrc, workingDirectory); err == nil && importPath != "." && !strings.HasPrefix(importPath, "..") {
+			return workingDirectory, filepath.Join("gopath", "src", importPath), true
+		}
+	}
+	return workingDirectory, "src", false
+}
+
+// Copies a source tree, leaving out hidden dirs (e.g. .git) & the given dirs (e.g. the output dir)
+func copySourceTree(srcRoot, destRoot string, skipDirs []string) error {
+	skip := map[string]bool{}
+	for _, dir := range skipDirs {
+		if abs, err := filepath.Abs(dir); err == nil {
+			skip[abs] = true
+		}
+	}
+	return filepath.Walk(srcRoot, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(srcRoot, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(destRoot, relativePath)
+		switch {
+		case fi.IsDir():
+			if path != srcRoot && (strings.HasPrefix(fi.Name(), ".") || skip[path]) {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(dest, 0755)
+		case fi.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(target, dest)
+		case fi.Mode().IsRegular():
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return ioutil.WriteFile(dest, data, fi.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+// The task params for one verification build: paths inside the copy of the source, output in buildDir, & an artifact manifest of its own
+func (tp TaskParams) forVerification(srcRoot, copyRoot, buildDir string) TaskParams {
+	inCopy := func(path string) string {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return path
+		}
+		relativePath, err := filepath.Rel(srcRoot, abs)
+		if err != nil || strings.HasPrefix(relativePath, "..") {
+			return path
+		}
+		return filepath.Join(copyRoot, relativePath)
+	}
+	vtp := tp
+	vtp.WorkingDirectory = inCopy(tp.WorkingDirectory)
+	vtp.MainDirs = []string{}
+	for _, mainDir := range tp.MainDirs {
+		vtp.MainDirs = append(vtp.MainDirs, inCopy(mainDir))
+	}
+	vtp.OutDestRoot = filepath.Join(buildDir, "out")
+	vtp.Settings = reproducibleSettings(tp.Settings, buildDir)
+	vtp.state = nil
+	vtp.artifacts = loadArtifactManifest(filepath.Join(vtp.OutDestRoot, tp.Settings.GetFullVersionName()))
+	return vtp
+}
+
+// Builds, archives & packages the binaries for a platform, with its own build cache.
+// Returns the artifacts' checksums, keyed by path (relative to the output dir)
+func buildForVerification(logger *log.Logger, dest platforms.Platform, vtp TaskParams) (map[string]string, error) {
+	outDestRoot := vtp.OutDestRoot
+	artifactPaths := []string{}
+	exes := []string{}
+	for _, mainDir := range vtp.mainDirsFor(dest) {
+		absoluteBin, err := xcPlat(logger, dest, mainDir, vtp.settingsFor(dest, mainDir), outDestRoot, vtp.exeName(mainDir))
+		if err != nil {
+			return nil, err
+		}
+		err = vtp.artifacts.register(ARTIFACT_BINARY, TASK_XC, dest, mainDir, absoluteBin)
+		if err != nil {
+			return nil, err
+		}
+		artifactPaths = append(artifactPaths, absoluteBin)
+		if vtp.binarySettings(mainDir).IsPackaged() {
+			exes = append(exes, absoluteBin)
+		}
+	}
+	settings := vtp.Settings.ForPlatform(dest)
+	archivers := []archive.Archiver{archive.Zip, archive.TarGz}
+	for i, ending := range []string{"zip", "tar.gz"} {
+		archivePath, err := archivePlat(logger, dest, exes, vtp.AppName, vtp.WorkingDirectory, outDestRoot, settings, ending, archivers[i], false)
+		if err != nil {
+			return nil, err
+		}
+		artifactPaths = append(artifactPaths, archivePath)
+	}
+	//e.g. .deb files
+	packages, err := pkgBuildPlat(logger, dest, vtp)
+	if err != nil {
+		return nil, err
+	}
+	artifactPaths = append(artifactPaths, packages...)
+	checksums := map[string]string{}
+	if core.IsDryRun() {
+		return checksums, nil
+	}
+	for _, artifactPath := range artifactPaths {
+		relativePath, err := filepath.Rel(outDestRoot, artifactPath)
+		if err != nil {
+			return nil, err
+		}
+		_, checksums[filepath.ToSlash(relativePath)], err = core.FileSizeAndSha256(artifactPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return checksums, nil
+}
+
+// settings for a verification build: always Reproducible, with a build cache in buildDir
+func reproducibleSettings(settings config.Settings, buildDir string) config.Settings {
+	buildSettings := config.BuildSettings{}
+	if settings.BuildSettings != nil {
+		buildSettings = *settings.BuildSettings
+	}
+	isReproducible := true
+	buildSettings.Reproducible = &isReproducible
+	settings.BuildSettings = &buildSettings
+	settings.Env = append(append([]string{}, settings.Env...), "GOCACHE="+filepath.Join(buildDir, "gocache"))
+	return settings
+}
+
+// An error listing any artifacts whose checksums differ
+func compareChecksums(a, b map[string]string) error {
+	paths := []string{}
+	for path := range a {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	differences := []string{}
+	for _, path := range paths {
+		if a[path] != b[path] {
+			differences = append(differences, fmt.Sprintf("%s (sha256 %s != %s)", path, a[path], b[path]))
+		}
+	}
+	if len(differences) > 0 {
+		return fmt.Errorf("not reproducible: %s", strings.Join(differences, ", "))
+	}
+	return nil
+}
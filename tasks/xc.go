@@ -33,6 +33,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 )
 
 //runs automatically
@@ -45,45 +46,75 @@ func init() {
 		map[string]interface{}{"GOARM": "",
 			//"validation" : "tcBinExists,exeParse",
 			"validateToolchain":    true,
+			"validateCgo":          true,
 			"verifyExe":            true,
-			"autoRebuildToolchain": true}})
+			"autoRebuildToolchain": true},
+		nil})
 }
 
 func runTaskXC(tp TaskParams) error {
 	if len(tp.DestPlatforms) == 0 {
 		return errors.New("No valid platforms specified")
 	}
-	success := 0
-	var err error
 	appName := core.GetAppName(tp.WorkingDirectory)
 	outDestRoot := core.GetOutDestRoot(appName, tp.Settings.ArtifactsDest, tp.WorkingDirectory)
 	log.Printf("mainDirs : %v", tp.MainDirs)
-	for _, dest := range tp.DestPlatforms {
-		for _, mainDir := range tp.MainDirs {
-			exeName := filepath.Base(mainDir)
-			absoluteBin, err := xcPlat(dest.Os, dest.Arch, mainDir, tp.Settings, outDestRoot, exeName)
+	//0.11.x each binary has its own platforms (see Binaries)
+	jobs := tp.binaryJobs(tp.DestPlatforms)
+	//0.11.x platforms are built concurrently
+	return runPlatformJobs(tp, TASK_XC, jobs, func(job platformJob, logger *log.Logger) error {
+		dest := job.Platform
+		//0.11.x incremental builds
+		if tp.state.isUpToDate(TASK_XC, job) {
+			logger.Printf("Up to date. Skipping (use -rebuild to build anyway)")
+			return nil
+		}
+		exeName := tp.exeName(job.MainDir)
+		//0.11.x with any PlatformOverrides & the binary's own settings
+		settings := tp.settingsFor(dest, job.MainDir)
+		absoluteBin, err := xcPlat(logger, dest, job.MainDir, settings, outDestRoot, exeName)
+		if err != nil {
+			logger.Printf("Error: %v", err)
+			if executils.IsLegacyToolchain(settings.GoRoot) {
+				logger.Printf("Have you run `goxc -t` for this platform (%s,%s)???", dest.Arch, dest.Os)
+			}
+			return err
+		}
+		isVerifyExe := settings.GetTaskSettingBool(TASK_XC, "verifyExe")
+		//nothing to verify in a dry run
+		if isVerifyExe && !core.IsDryRun() {
+			err = exefileparse.Test(absoluteBin, dest.Arch, dest.Os)
 			if err != nil {
-				log.Printf("Error: %v", err)
-				log.Printf("Have you run `goxc -t` for this platform (%s,%s)???", dest.Arch, dest.Os)
+				logger.Printf("Error: %v", err)
+				logger.Printf("Something fishy is going on: have you run `goxc -t` for this platform (%s,%s)???", dest.Arch, dest.Os)
 				return err
-			} else {
-				success = success + 1
-				isVerifyExe := tp.Settings.GetTaskSettingBool(TASK_XC, "verifyExe")
-				if isVerifyExe {
-					err = exefileparse.Test(absoluteBin, dest.Arch, dest.Os)
-					if err != nil {
-						log.Printf("Error: %v", err)
-						log.Printf("Something fishy is going on: have you run `goxc -t` for this platform (%s,%s)???", dest.Arch, dest.Os)
-						return err
-					}
-				}
 			}
 		}
+		tp.state.record(TASK_XC, job, []string{absoluteBin})
+		return tp.artifacts.register(ARTIFACT_BINARY, TASK_XC, dest, job.MainDir, absoluteBin)
+	})
+}
+
+// Rebuilds any missing binaries for the given platform.
+// 0.11.x binaries can be missing when 'xc' was skipped as up-to-date, but 'rmbin' removed them last time.
+func ensureExes(logger *log.Logger, tp TaskParams, dest platforms.Platform) error {
+	if core.IsDryRun() {
+		return nil
 	}
-	//0.6 return error if no platforms succeeded.
-	if success < 1 {
-		log.Printf("No successes!")
-		return err
+	for _, mainDir := range tp.mainDirsFor(dest) {
+		exeName := tp.exeName(mainDir)
+		if exists, _ := core.FileExists(tp.binaryPath(dest, mainDir)); !exists {
+			logger.Printf("Binary for %s is missing. Rebuilding it", exeName)
+			absoluteBin, err := xcPlat(logger, dest, mainDir, tp.settingsFor(dest, mainDir), tp.OutDestRoot, exeName)
+			if err != nil {
+				return err
+			}
+			tp.state.record(TASK_XC, platformJob{dest, mainDir}, []string{absoluteBin})
+			err = tp.artifacts.register(ARTIFACT_BINARY, TASK_XC, dest, mainDir, absoluteBin)
+			if err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -106,10 +137,13 @@ func validatePlatToolchainPackageVersion(goos, arch, goroot string) error {
 	nr, err := os.Open(platPkgFileRuntime)
 	if err != nil {
 		log.Printf("Could not validate toolchain version: %v", err)
+		return nil
 	}
+	defer nr.Close()
 	tr, err := ar.NewReader(nr)
 	if err != nil {
 		log.Printf("Could not validate toolchain version: %v", err)
+		return nil
 	}
 	for {
 		h, err := tr.Next()
@@ -176,47 +210,65 @@ func validatePlatToolchainBinExists(goos, arch, goroot string) error {
 	return err
 }
 
+// toolchain (re)builds share the GOROOT, so they must never run concurrently.
+var toolchainLock sync.Mutex
+
 // xcPlat: Cross compile for a particular platform
 // 0.3.0 - breaking change - changed 'call []string' to 'workingDirectory string'.
-func xcPlat(goos, arch string, workingDirectory string, settings config.Settings, outDestRoot string, exeName string) (string, error) {
+// 0.11.x - added logger, because platforms are built concurrently. Takes the Platform, for its Variant
+func xcPlat(logger *log.Logger, dest platforms.Platform, workingDirectory string, settings config.Settings, outDestRoot string, exeName string) (string, error) {
+	goos, arch := dest.Os, dest.Arch
 	isValidateToolchain := settings.GetTaskSettingBool(TASK_XC, "validateToolchain")
 	goroot := settings.GoRoot
-	if isValidateToolchain {
+	//0.11.x Go 1.5+ cross-compiles with just GOOS & GOARCH, so there's no per-platform toolchain to validate
+	if isValidateToolchain && executils.IsLegacyToolchain(goroot) {
+		toolchainLock.Lock()
 		err := validateToolchain(goos, arch, goroot)
 		if err != nil {
-			log.Printf("Toolchain not ready. Re-building toolchain. (%v)", err)
+			logger.Printf("Toolchain not ready. Re-building toolchain. (%v)", err)
 			isAutoToolchain := settings.GetTaskSettingBool(TASK_XC, "autoRebuildToolchain")
 			if isAutoToolchain {
 				err = buildToolchain(goos, arch, settings)
 			}
-			if err != nil {
-				return "", err
-			}
 		}
+		toolchainLock.Unlock()
+		if err != nil {
+			return "", err
+		}
+	}
+	//0.11.x validates the C toolchain before building
+	cgoEnvExtra, err := cgoEnv(logger, dest, settings)
+	if err != nil {
+		return "", err
 	}
-	log.Printf("building %s for platform %s_%s.", exeName, goos, arch)
-	relativeDir := filepath.Join(settings.GetFullVersionName(), goos+"_"+arch)
+	logger.Printf("building %s for platform %s.", exeName, dest.Name())
+	relativeDir := filepath.Join(settings.GetFullVersionName(), dest.Name())
 
 	outDir := filepath.Join(outDestRoot, relativeDir)
-	err := os.MkdirAll(outDir, 0755)
+	err = core.MkdirAll(outDir, 0755)
 	if err != nil {
 		return "", err
 	}
 	args := []string{}
-	relativeBin := core.GetRelativeBin(goos, arch, exeName, false, settings.GetFullVersionName())
+	relativeBin := core.GetRelativeBin(goos, dest.ArchName(), exeName, false, settings.GetFullVersionName())
 	absoluteBin := filepath.Join(outDestRoot, relativeBin)
 	//args = append(args, executils.GetLdFlagVersionArgs(settings.GetFullVersionName())...)
 	args = append(args, "-o", absoluteBin, ".")
 	//log.Printf("building %s", exeName)
 	//v0.8.5 no longer using CGO_ENABLED
 	envExtra := []string{"GOOS=" + goos, "GOARCH=" + arch}
-	if goos == platforms.LINUX && arch == platforms.ARM {
+	//0.11.x CGO_ENABLED, CC, CXX & cgo flags, from the platform's Cgo settings
+	envExtra = append(envExtra, cgoEnvExtra...)
+	if dest.Variant != "" {
+		//0.11.x e.g. GOARM=7 or GOAMD64=v3
+		envExtra = append(envExtra, dest.VariantEnv()...)
+	} else if goos == platforms.LINUX && arch == platforms.ARM {
 		// see http://dave.cheney.net/2012/09/08/an-introduction-to-cross-compilation-with-go
 		goarm := settings.GetTaskSettingString(TASK_XC, "GOARM")
 		if goarm != "" {
 			envExtra = append(envExtra, "GOARM="+goarm)
 		}
 	}
-	err = executils.InvokeGo(workingDirectory, "build", args, envExtra, settings)
+	err = executils.InvokeGoWithLogger(logger, workingDirectory, "build", args, envExtra, settings)
 	return absoluteBin, err
 }
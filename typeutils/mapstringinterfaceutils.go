@@ -96,6 +96,23 @@ func ToMapStringMapStringInterface(v interface{}, k string) (map[string]map[stri
 	return nil, fmt.Errorf("%s should be a json map[string]map[string]interface{}, not a %T", k, v)
 }
 
+// coerce interface{} to map[string][]string
+func ToMapStringStringSlice(v interface{}, k string) (map[string][]string, error) {
+	switch typedV := v.(type) {
+	case map[string]interface{}:
+		ret := make(map[string][]string)
+		for subK, subV := range typedV {
+			typedSubV, err := ToStringSlice(subV, k+"."+subK)
+			if err != nil {
+				return nil, err
+			}
+			ret[subK] = typedSubV
+		}
+		return ret, nil
+	}
+	return nil, fmt.Errorf("%s should be a json map[string][]string, not a %T", k, v)
+}
+
 // merge nested maps (first argument takes priority)
 // note that lists are replaced, not merged
 func MergeMapsStringMapStringInterface(high, low map[string]map[string]interface{}) map[string]map[string]interface{} {
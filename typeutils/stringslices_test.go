@@ -20,3 +20,19 @@ func TestStringSliceEquals(t *testing.T) {
 		t.Fatalf("unexpected result %v == %v", actual2, expected)
 	}
 }
+
+func TestClosestMatch(t *testing.T) {
+	candidates := []string{"verifyExe", "validateToolchain", "GOARM"}
+	if m := ClosestMatch("verifyExes", candidates); m != "verifyExe" {
+		t.Errorf("Expected 'verifyExe', got '%s'", m)
+	}
+	if m := ClosestMatch("goarm", candidates); m != "GOARM" {
+		t.Errorf("Expected 'GOARM', got '%s'", m)
+	}
+	if m := ClosestMatch("somethingElse", candidates); m != "" {
+		t.Errorf("Expected no match, got '%s'", m)
+	}
+	if d := Levenshtein("kitten", "sitting"); d != 3 {
+		t.Errorf("Expected distance 3, got %d", d)
+	}
+}
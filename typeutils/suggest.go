@@ -0,0 +1,76 @@
+package typeutils
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"strings"
+)
+
+// Levenshtein (edit) distance between 2 strings
+// 0.11.x
+func Levenshtein(a, b string) int {
+	ra := []rune(a)
+	rb := []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// The candidate closest to the given (probably misspelled) value, ignoring case.
+// Returns "" if nothing is close enough to be a likely typo.
+// 0.11.x
+func ClosestMatch(value string, candidates []string) string {
+	best := ""
+	bestDistance := -1
+	//allow roughly one typo per 3 characters, and at least 2
+	maxDistance := len(value) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+	for _, candidate := range candidates {
+		distance := Levenshtein(strings.ToLower(value), strings.ToLower(candidate))
+		if distance <= maxDistance && (bestDistance == -1 || distance < bestDistance) {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+	return best
+}